@@ -0,0 +1,116 @@
+package pool
+
+import (
+	"time"
+)
+
+// 服务IP健康评分参数
+const (
+	latencyAlpha     = 0.3              // 延迟 EWMA 的新样本权重
+	errorAlpha       = 0.3              // 错误率 EWMA 的新样本权重
+	errorPenalty     = time.Second      // 错误率为1时评分增加的时长
+	FailureThreshold = 3                // 连续失败达到该次数时熔断
+	OpenDuration     = 30 * time.Second // 熔断后等待该时长进入半开状态
+)
+
+// BreakerState 熔断器状态
+type BreakerState int
+
+const (
+	BreakerClosed   BreakerState = iota // 正常
+	BreakerOpen                         // 熔断，不参与选择
+	BreakerHalfOpen                     // 半开，允许一个探测请求
+)
+
+// String 返回熔断器状态的字符串表示
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// IPStats 服务IP健康评分快照
+type IPStats struct {
+	IP                  string        `json:"ip"`
+	Latency             time.Duration `json:"latency"`    // 响应延迟的 EWMA
+	ErrorRate           float64       `json:"error_rate"` // 错误率的 EWMA，范围 [0,1]
+	Score               time.Duration `json:"score"`      // 综合评分（延迟 + 错误率惩罚），越小越好
+	State               BreakerState  `json:"state"`
+	Successes           int64         `json:"successes"`
+	Failures            int64         `json:"failures"`
+	ConsecutiveFailures int           `json:"consecutive_failures"`
+}
+
+// ipHealth 单个服务IP的健康状态
+type ipHealth struct {
+	latency             float64 // 纳秒
+	errorRate           float64
+	state               BreakerState
+	openedAt            time.Time // 熔断时间
+	probeAt             time.Time // 半开状态下发出探测请求的时间
+	successes           int64
+	failures            int64
+	consecutiveFailures int
+}
+
+// score 综合评分，未请求过的IP为0，会被优先尝试
+func (h *ipHealth) score() time.Duration {
+	return time.Duration(h.latency + h.errorRate*float64(errorPenalty))
+}
+
+// probeDue 熔断或半开状态下是否可以发出探测请求（半开探测未返回结果超过 OpenDuration 时允许重新探测）
+func (h *ipHealth) probeDue(now time.Time) bool {
+	switch h.state {
+	case BreakerOpen:
+		return now.Sub(h.openedAt) >= OpenDuration
+	case BreakerHalfOpen:
+		return now.Sub(h.probeAt) >= OpenDuration
+	default:
+		return false
+	}
+}
+
+// recordSuccess 记录一次成功请求
+func (h *ipHealth) recordSuccess(latency time.Duration) {
+	if h.successes == 0 {
+		h.latency = float64(latency)
+	} else {
+		h.latency += latencyAlpha * (float64(latency) - h.latency)
+	}
+	h.errorRate -= errorAlpha * h.errorRate
+	h.successes++
+	h.consecutiveFailures = 0
+	h.state = BreakerClosed
+}
+
+// recordFailure 记录一次失败请求，连续失败达到阈值或半开探测失败时熔断
+func (h *ipHealth) recordFailure(now time.Time) {
+	h.errorRate += errorAlpha * (1 - h.errorRate)
+	h.failures++
+	h.consecutiveFailures++
+	if h.state == BreakerHalfOpen || h.consecutiveFailures >= FailureThreshold {
+		h.state = BreakerOpen
+		h.openedAt = now
+	}
+}
+
+// stats 返回健康状态快照
+func (h *ipHealth) stats(ip string) IPStats {
+	return IPStats{
+		IP:                  ip,
+		Latency:             time.Duration(h.latency),
+		ErrorRate:           h.errorRate,
+		Score:               h.score(),
+		State:               h.state,
+		Successes:           h.successes,
+		Failures:            h.failures,
+		ConsecutiveFailures: h.consecutiveFailures,
+	}
+}
@@ -9,11 +9,10 @@ import (
 	"time"
 )
 
-// ServiceIPManager 服务IP管理器
+// ServiceIPManager 服务IP管理器，按健康评分选择服务IP
 type ServiceIPManager struct {
 	serviceIPs []string
-	currentIP  string
-	failedIPs  map[string]time.Time // 记录失败的IP和失败时间
+	health     map[string]*ipHealth // 各服务IP的延迟、错误率和熔断状态
 	updatedAt  time.Time
 	mutex      sync.RWMutex
 }
@@ -21,11 +20,11 @@ type ServiceIPManager struct {
 // NewServiceIPManager 创建服务IP管理器
 func NewServiceIPManager() *ServiceIPManager {
 	return &ServiceIPManager{
-		failedIPs: make(map[string]time.Time),
+		health: make(map[string]*ipHealth),
 	}
 }
 
-// UpdateServiceIPs 更新服务IP列表
+// UpdateServiceIPs 更新服务IP列表，保留仍在列表中的IP的健康状态
 func (m *ServiceIPManager) UpdateServiceIPs(ips []string) {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
@@ -34,64 +33,125 @@ func (m *ServiceIPManager) UpdateServiceIPs(ips []string) {
 	copy(m.serviceIPs, ips)
 	m.updatedAt = time.Now()
 
-	// 如果当前IP不在新列表中，清空当前IP
-	if m.currentIP != "" {
-		found := false
-		for _, ip := range ips {
-			if ip == m.currentIP {
-				found = true
-				break
-			}
-		}
-		if !found {
-			m.currentIP = ""
+	health := make(map[string]*ipHealth, len(ips))
+	for _, ip := range ips {
+		if h, ok := m.health[ip]; ok {
+			health[ip] = h
+		} else {
+			health[ip] = &ipHealth{}
 		}
 	}
+	m.health = health
 }
 
-// GetAvailableIP 获取可用的服务IP
+// GetAvailableIP 获取评分最好的可用服务IP：到期的熔断IP优先作为探测请求，
+// 其次为未熔断IP中评分最小的；全部熔断或半开时返回最早熔断的IP
 func (m *ServiceIPManager) GetAvailableIP() (string, error) {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
-	if len(m.serviceIPs) == 0 {
+	ip, _ := m.selectLocked("", time.Now())
+	if ip == "" {
 		return "", fmt.Errorf("no service IPs available")
 	}
+	return ip, nil
+}
 
-	// 正常情况下使用当前IP
-	if m.currentIP != "" {
-		// 检查当前IP是否在失败列表中
-		if failTime, exists := m.failedIPs[m.currentIP]; !exists ||
-			time.Since(failTime) > 5*time.Minute { // 5分钟后重试失败的IP
-			return m.currentIP, nil
-		}
+// GetAlternativeIP 获取 exclude 以外评分最好的可用服务IP（用于对冲请求），没有未熔断的其他IP时返回 false
+func (m *ServiceIPManager) GetAlternativeIP(exclude string) (string, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	ip, fallback := m.selectLocked(exclude, time.Now())
+	if ip == "" || fallback {
+		return "", false
 	}
+	return ip, true
+}
 
-	// 异常情况下轮转到下一个可用IP
-	for _, ip := range m.serviceIPs {
-		if failTime, exists := m.failedIPs[ip]; !exists ||
-			time.Since(failTime) > 5*time.Minute {
-			m.currentIP = ip
-			return ip, nil
+// selectLocked 选择 exclude 以外的服务IP，选中到期的熔断IP时转为半开状态；
+// 没有未熔断IP时返回最早熔断的IP，fallback 为 true
+func (m *ServiceIPManager) selectLocked(exclude string, now time.Time) (ip string, fallback bool) {
+	var best, probe, oldest string
+	for _, candidate := range m.serviceIPs {
+		if candidate == exclude {
+			continue
+		}
+		h := m.health[candidate]
+		switch {
+		case h.probeDue(now):
+			if probe == "" {
+				probe = candidate
+			}
+		case h.state == BreakerClosed:
+			if best == "" || h.score() < m.health[best].score() {
+				best = candidate
+			}
+		default:
+			// 熔断或探测中的半开IP，仅在没有未熔断IP时使用
+			if oldest == "" || h.openedAt.Before(m.health[oldest].openedAt) {
+				oldest = candidate
+			}
 		}
 	}
 
-	// 如果所有IP都失败，返回第一个IP（可能已经恢复）
-	m.currentIP = m.serviceIPs[0]
-	return m.currentIP, nil
+	if probe != "" {
+		h := m.health[probe]
+		h.state = BreakerHalfOpen
+		h.probeAt = now
+		return probe, false
+	}
+	if best != "" {
+		return best, false
+	}
+	return oldest, oldest != ""
 }
 
-// MarkIPFailed 标记IP失败
-func (m *ServiceIPManager) MarkIPFailed(ip string) {
+// RecordSuccess 记录服务IP的一次成功响应及其延迟
+func (m *ServiceIPManager) RecordSuccess(ip string, latency time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if h, ok := m.health[ip]; ok {
+		h.recordSuccess(latency)
+	}
+}
+
+// RecordFailure 记录服务IP的一次失败，连续失败达到 FailureThreshold 时熔断
+func (m *ServiceIPManager) RecordFailure(ip string) {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
-	m.failedIPs[ip] = time.Now()
+	if h, ok := m.health[ip]; ok {
+		h.recordFailure(time.Now())
+	}
+}
+
+// ReleaseProbe 请求被调用方取消、没有结果时释放半开探测，允许立即重新探测
+func (m *ServiceIPManager) ReleaseProbe(ip string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 
-	// 如果当前IP失败，清空当前IP，下次会自动选择其他IP
-	if m.currentIP == ip {
-		m.currentIP = ""
+	if h, ok := m.health[ip]; ok && h.state == BreakerHalfOpen {
+		h.probeAt = time.Time{}
+	}
+}
+
+// MarkIPFailed 标记IP失败，等同于 RecordFailure
+func (m *ServiceIPManager) MarkIPFailed(ip string) {
+	m.RecordFailure(ip)
+}
+
+// Stats 返回各服务IP的健康评分，顺序与服务IP列表一致
+func (m *ServiceIPManager) Stats() []IPStats {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	stats := make([]IPStats, 0, len(m.serviceIPs))
+	for _, ip := range m.serviceIPs {
+		stats = append(stats, m.health[ip].stats(ip))
 	}
+	return stats
 }
 
 // GetServiceIPs 获取所有服务IP
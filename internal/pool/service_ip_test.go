@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 )
@@ -16,8 +17,8 @@ func TestNewServiceIPManager(t *testing.T) {
 		t.Fatal("NewServiceIPManager() returned nil")
 	}
 
-	if manager.failedIPs == nil {
-		t.Error("NewServiceIPManager() failedIPs not initialized")
+	if manager.health == nil {
+		t.Error("NewServiceIPManager() health not initialized")
 	}
 
 	if !manager.IsEmpty() {
@@ -107,6 +108,39 @@ func TestServiceIPManager_MarkIPFailed(t *testing.T) {
 	}
 }
 
+func TestServiceIPManager_GetAlternativeIP(t *testing.T) {
+	manager := NewServiceIPManager()
+	if _, ok := manager.GetAlternativeIP(""); ok {
+		t.Error("GetAlternativeIP() should fail without service IPs")
+	}
+
+	manager.UpdateServiceIPs([]string{"1.1.1.1", "2.2.2.2", "3.3.3.3"})
+	current, _ := manager.GetAvailableIP()
+
+	ip, ok := manager.GetAlternativeIP(current)
+	if !ok || ip != "2.2.2.2" {
+		t.Errorf("GetAlternativeIP() = %s, %v, want 2.2.2.2, true", ip, ok)
+	}
+
+	// 跳过失败的IP，且不改变当前IP
+	manager.MarkIPFailed("2.2.2.2")
+	if ip, _ := manager.GetAlternativeIP(current); ip != "3.3.3.3" {
+		t.Errorf("GetAlternativeIP() = %s, want 3.3.3.3", ip)
+	}
+	if ip, _ := manager.GetAvailableIP(); ip != current {
+		t.Errorf("GetAvailableIP() = %s, want unchanged %s", ip, current)
+	}
+
+	// 其他IP均熔断时没有可用的对冲目标
+	for i := 0; i < FailureThreshold; i++ {
+		manager.MarkIPFailed("2.2.2.2")
+		manager.MarkIPFailed("3.3.3.3")
+	}
+	if _, ok := manager.GetAlternativeIP(current); ok {
+		t.Error("GetAlternativeIP() should fail when other IPs are open")
+	}
+}
+
 func TestServiceIPManager_FailedIPRecovery(t *testing.T) {
 	manager := NewServiceIPManager()
 	ips := []string{"1.2.3.4"}
@@ -125,6 +159,125 @@ func TestServiceIPManager_FailedIPRecovery(t *testing.T) {
 	}
 }
 
+func TestServiceIPManager_ScoreSelection(t *testing.T) {
+	manager := NewServiceIPManager()
+	manager.UpdateServiceIPs([]string{"1.1.1.1", "2.2.2.2", "3.3.3.3"})
+
+	manager.RecordSuccess("1.1.1.1", 80*time.Millisecond)
+	manager.RecordSuccess("2.2.2.2", 20*time.Millisecond)
+	manager.RecordSuccess("3.3.3.3", 10*time.Millisecond)
+	manager.RecordFailure("3.3.3.3")
+
+	// 3.3.3.3 延迟最低但有错误，评分不如 2.2.2.2
+	if ip, _ := manager.GetAvailableIP(); ip != "2.2.2.2" {
+		t.Errorf("GetAvailableIP() = %s, want 2.2.2.2", ip)
+	}
+
+	// EWMA 延迟
+	manager.RecordSuccess("2.2.2.2", 300*time.Millisecond)
+	stats := manager.Stats()
+	if len(stats) != 3 || stats[1].IP != "2.2.2.2" {
+		t.Fatalf("Stats() = %+v, want 3 entries in list order", stats)
+	}
+	if want := 20*time.Millisecond + time.Duration(latencyAlpha*float64(280*time.Millisecond)); stats[1].Latency != want {
+		t.Errorf("Stats() latency = %v, want %v", stats[1].Latency, want)
+	}
+	if stats[2].ErrorRate != errorAlpha || stats[2].Successes != 1 || stats[2].Failures != 1 {
+		t.Errorf("Stats() 3.3.3.3 = %+v, want error rate %v with 1 success and 1 failure", stats[2], errorAlpha)
+	}
+	if ip, _ := manager.GetAvailableIP(); ip != "1.1.1.1" {
+		t.Errorf("GetAvailableIP() after latency increase = %s, want 1.1.1.1", ip)
+	}
+
+	// 更新列表时保留仍存在的IP的评分
+	manager.UpdateServiceIPs([]string{"2.2.2.2", "4.4.4.4"})
+	if stats := manager.Stats(); stats[0].Successes != 2 || stats[1].Successes != 0 {
+		t.Errorf("Stats() after update = %+v, want 2.2.2.2 kept and 4.4.4.4 new", stats)
+	}
+}
+
+func TestServiceIPManager_CircuitBreaker(t *testing.T) {
+	manager := NewServiceIPManager()
+	manager.UpdateServiceIPs([]string{"1.1.1.1", "2.2.2.2"})
+	manager.RecordSuccess("2.2.2.2", 500*time.Millisecond)
+
+	for i := 0; i < FailureThreshold; i++ {
+		manager.RecordFailure("1.1.1.1")
+	}
+	if state := manager.Stats()[0].State; state != BreakerOpen {
+		t.Fatalf("state after %d failures = %v, want open", FailureThreshold, state)
+	}
+	if ip, _ := manager.GetAvailableIP(); ip != "2.2.2.2" {
+		t.Errorf("GetAvailableIP() = %s, want 2.2.2.2 while 1.1.1.1 is open", ip)
+	}
+
+	// 熔断到期后放行一个探测请求
+	manager.health["1.1.1.1"].openedAt = time.Now().Add(-OpenDuration)
+	if ip, _ := manager.GetAvailableIP(); ip != "1.1.1.1" {
+		t.Errorf("GetAvailableIP() = %s, want 1.1.1.1 as half-open probe", ip)
+	}
+	if ip, _ := manager.GetAvailableIP(); ip != "2.2.2.2" {
+		t.Errorf("GetAvailableIP() = %s, want 2.2.2.2 while probe is in flight", ip)
+	}
+	if state := manager.Stats()[0].State; state != BreakerHalfOpen {
+		t.Errorf("state during probe = %v, want half-open", state)
+	}
+
+	// 探测失败重新熔断，探测成功关闭
+	manager.RecordFailure("1.1.1.1")
+	if state := manager.Stats()[0].State; state != BreakerOpen {
+		t.Errorf("state after failed probe = %v, want open", state)
+	}
+	manager.health["1.1.1.1"].openedAt = time.Now().Add(-OpenDuration)
+	manager.GetAvailableIP()
+	manager.RecordSuccess("1.1.1.1", 10*time.Millisecond)
+	if stats := manager.Stats()[0]; stats.State != BreakerClosed || stats.ConsecutiveFailures != 0 {
+		t.Errorf("stats after successful probe = %+v, want closed", stats)
+	}
+	if BreakerHalfOpen.String() != "half-open" {
+		t.Errorf("BreakerHalfOpen.String() = %s", BreakerHalfOpen.String())
+	}
+}
+
+func TestServiceIPManager_ProbeInFlight(t *testing.T) {
+	manager := NewServiceIPManager()
+	manager.UpdateServiceIPs([]string{"1.1.1.1"})
+	for i := 0; i < FailureThreshold; i++ {
+		manager.RecordFailure("1.1.1.1")
+	}
+	manager.health["1.1.1.1"].openedAt = time.Now().Add(-OpenDuration)
+
+	if ip, err := manager.GetAvailableIP(); err != nil || ip != "1.1.1.1" {
+		t.Fatalf("GetAvailableIP() = %q, %v, want probe to 1.1.1.1", ip, err)
+	}
+
+	// 探测进行中时并发选择仍返回该IP，不返回空IP
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if ip, err := manager.GetAvailableIP(); err != nil || ip != "1.1.1.1" {
+				t.Errorf("GetAvailableIP() during probe = %q, %v, want 1.1.1.1", ip, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if _, ok := manager.GetAlternativeIP(""); ok {
+		t.Error("GetAlternativeIP() should not return a half-open IP while its probe is in flight")
+	}
+
+	// 探测被取消后允许立即重新探测
+	manager.ReleaseProbe("1.1.1.1")
+	if ip, ok := manager.GetAlternativeIP(""); !ok || ip != "1.1.1.1" {
+		t.Errorf("GetAlternativeIP() after ReleaseProbe = %q, %v, want new probe", ip, ok)
+	}
+	if h := manager.health["1.1.1.1"]; h.state != BreakerHalfOpen || h.probeAt.IsZero() {
+		t.Errorf("probe not restarted after ReleaseProbe: %+v", h)
+	}
+}
+
 func TestNewBootstrapManager(t *testing.T) {
 	bootstrapIPs := []string{"1.2.3.4", "5.6.7.8"}
 	domain := "example.com"
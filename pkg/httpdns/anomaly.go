@@ -0,0 +1,99 @@
+package httpdns
+
+import (
+	"time"
+)
+
+// answerAnomaly 单个域名连续异常结果的统计
+type answerAnomaly struct {
+	count int       // 连续异常次数
+	first time.Time // 首次异常时间
+}
+
+// lastKnownGoodEnabled 是否启用异常结果保护
+func (r *Resolver) lastKnownGoodEnabled() bool {
+	return r.config.LastKnownGoodAttempts > 0 || r.config.LastKnownGoodDuration > 0
+}
+
+// keepLastKnownGood 新结果为空或缺少原有地址族时，在保护范围内返回原有缓存结果，否则返回 nil
+func (r *Resolver) keepLastKnownGood(domain string, queryType QueryType, result *ResolveResult) *ResolveResult {
+	if !r.lastKnownGoodEnabled() {
+		return nil
+	}
+
+	entry, ok := r.cacheManager.GetStale(domain, -1)
+	if !ok || !isDegradedAnswer(entry, result, queryType, r.config.LastKnownGoodShrinkRatio) {
+		r.clearAnomaly(domain)
+		return nil
+	}
+
+	anomaly := r.recordAnomaly(domain)
+	keep := (r.config.LastKnownGoodAttempts <= 0 || anomaly.count <= r.config.LastKnownGoodAttempts) &&
+		(r.config.LastKnownGoodDuration <= 0 || time.Since(anomaly.first) < r.config.LastKnownGoodDuration)
+	recordAnswerAnomaly(r.metrics, keep)
+
+	if !keep {
+		if r.config.Logger != nil {
+			r.config.Logger.Printf("Accepting degraded answer for %s after %d anomalies since %v",
+				domain, anomaly.count, anomaly.first.Format(time.RFC3339))
+		}
+		r.clearAnomaly(domain)
+		return nil
+	}
+
+	if r.config.Logger != nil {
+		r.config.Logger.Printf("Ignoring degraded answer for %s (IPv4=%d, IPv6=%d), keeping last known good (anomaly %d)",
+			domain, len(result.IPv4), len(result.IPv6), anomaly.count)
+	}
+
+	kept := entry.ToResolveResult(domain)
+	kept.ClientIP = result.ClientIP
+	return kept
+}
+
+// isDegradedAnswer 判断新结果相对缓存是否退化：本次查询的地址族在缓存中非空，
+// 而新结果为空或地址数少于缓存的 shrinkRatio
+func isDegradedAnswer(entry *CacheEntry, result *ResolveResult, queryType QueryType, shrinkRatio float64) bool {
+	wantIPv4 := queryType != QueryIPv6
+	wantIPv6 := queryType != QueryIPv4
+
+	if wantIPv4 && isShrunk(len(entry.IPv4), len(result.IPv4), shrinkRatio) {
+		return true
+	}
+	if wantIPv6 && isShrunk(len(entry.IPv6), len(result.IPv6), shrinkRatio) {
+		return true
+	}
+	return false
+}
+
+// isShrunk 判断单个地址族的地址数是否从 cached 骤减到 got
+func isShrunk(cached, got int, shrinkRatio float64) bool {
+	if cached == 0 {
+		return false
+	}
+	return got == 0 || float64(got) < float64(cached)*shrinkRatio
+}
+
+// recordAnomaly 记录一次异常结果，返回更新后的统计快照
+func (r *Resolver) recordAnomaly(domain string) answerAnomaly {
+	r.anomalyMu.Lock()
+	defer r.anomalyMu.Unlock()
+
+	if r.anomalies == nil {
+		r.anomalies = make(map[string]*answerAnomaly)
+	}
+	anomaly, ok := r.anomalies[domain]
+	if !ok {
+		anomaly = &answerAnomaly{first: time.Now()}
+		r.anomalies[domain] = anomaly
+	}
+	anomaly.count++
+	return *anomaly
+}
+
+// clearAnomaly 清除域名的异常统计
+func (r *Resolver) clearAnomaly(domain string) {
+	r.anomalyMu.Lock()
+	delete(r.anomalies, domain)
+	r.anomalyMu.Unlock()
+}
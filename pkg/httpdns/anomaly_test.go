@@ -0,0 +1,246 @@
+package httpdns
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newAnomalyTestServer 创建返回可变解析结果的测试服务器
+func newAnomalyTestServer(t *testing.T) (*httptest.Server, func(HTTPDNSResponse)) {
+	var mu sync.Mutex
+	answer := HTTPDNSResponse{Host: "example.com", TTL: 60}
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/test123/ss" {
+			serverAddr := server.URL[7:]
+			response := map[string]interface{}{
+				"service_ip": []string{serverAddr},
+			}
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		mu.Lock()
+		response := answer
+		mu.Unlock()
+		if r.URL.Path == "/test123/resolve" {
+			json.NewEncoder(w).Encode(BatchResolveResponse{DNS: []HTTPDNSResponse{response}})
+			return
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func(resp HTTPDNSResponse) {
+		mu.Lock()
+		answer = resp
+		mu.Unlock()
+	}
+}
+
+// seedExpiredEntry 写入一条已过期的缓存，保证下次解析会请求网络
+func seedExpiredEntry(resolver *Resolver, ipv4, ipv6 []string) {
+	resolver.cacheManager.Set("example.com", &CacheEntry{
+		IPv4:      ipv4,
+		IPv6:      ipv6,
+		TTL:       60,
+		QueryTime: time.Now().Add(-2 * time.Minute),
+	})
+}
+
+func TestResolver_LastKnownGood_Attempts(t *testing.T) {
+	server, setAnswer := newAnomalyTestServer(t)
+	setAnswer(HTTPDNSResponse{Host: "example.com", TTL: 60})
+
+	config := DefaultConfig()
+	config.AccountID = "test123"
+	config.BootstrapIPs = []string{server.URL[7:]}
+	config.EnableMetrics = true
+	config.LastKnownGoodAttempts = 2
+
+	resolver := NewResolver(config)
+	seedExpiredEntry(resolver, []string{"1.2.3.4"}, nil)
+
+	// 前两次空结果被忽略，返回原有缓存
+	for i := 0; i < 2; i++ {
+		result, err := resolver.ResolveSingle(context.Background(), "example.com")
+		if err != nil {
+			t.Fatalf("ResolveSingle() #%d error = %v", i+1, err)
+		}
+		if len(result.IPv4) != 1 || result.IPv4[0].String() != "1.2.3.4" {
+			t.Errorf("ResolveSingle() #%d IPv4 = %v, want last known good", i+1, result.IPv4)
+		}
+	}
+
+	// 超过次数后接受空结果
+	result, err := resolver.ResolveSingle(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("ResolveSingle() error = %v", err)
+	}
+	if len(result.IPv4) != 0 {
+		t.Errorf("ResolveSingle() IPv4 = %v, want empty answer accepted", result.IPv4)
+	}
+
+	stats := resolver.GetMetrics()
+	if stats.AnswerAnomalies != 3 || stats.AnswersRejected != 2 {
+		t.Errorf("AnswerAnomalies = %d, AnswersRejected = %d, want 3 and 2", stats.AnswerAnomalies, stats.AnswersRejected)
+	}
+}
+
+func TestResolver_LastKnownGood_Duration(t *testing.T) {
+	server, setAnswer := newAnomalyTestServer(t)
+	setAnswer(HTTPDNSResponse{Host: "example.com", TTL: 60})
+
+	config := DefaultConfig()
+	config.AccountID = "test123"
+	config.BootstrapIPs = []string{server.URL[7:]}
+	config.LastKnownGoodDuration = 50 * time.Millisecond
+
+	resolver := NewResolver(config)
+	seedExpiredEntry(resolver, []string{"1.2.3.4"}, nil)
+
+	results, err := resolver.ResolveBatch(context.Background(), []string{"example.com"})
+	if err != nil || len(results[0].IPv4) != 1 {
+		t.Fatalf("ResolveBatch() = %+v, %v, want last known good", results, err)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+
+	results, err = resolver.ResolveBatch(context.Background(), []string{"example.com"})
+	if err != nil || len(results[0].IPv4) != 0 {
+		t.Errorf("ResolveBatch() = %+v, %v, want empty answer accepted after duration", results, err)
+	}
+}
+
+func TestResolver_LastKnownGood_Recovery(t *testing.T) {
+	server, setAnswer := newAnomalyTestServer(t)
+
+	config := DefaultConfig()
+	config.AccountID = "test123"
+	config.BootstrapIPs = []string{server.URL[7:]}
+	config.LastKnownGoodAttempts = 1
+
+	resolver := NewResolver(config)
+
+	// 缺少 IPv6 的部分结果被忽略
+	seedExpiredEntry(resolver, []string{"1.2.3.4"}, []string{"2001:db8::1"})
+	setAnswer(HTTPDNSResponse{Host: "example.com", IPs: []string{"5.6.7.8"}, TTL: 60})
+	result, err := resolver.ResolveSingle(context.Background(), "example.com")
+	if err != nil || len(result.IPv6) != 1 || result.IPv4[0].String() != "1.2.3.4" {
+		t.Fatalf("ResolveSingle() = %+v, %v, want last known good", result, err)
+	}
+
+	// 仅查询 IPv4 时不视为异常
+	result, err = resolver.ResolveSingle(context.Background(), "example.com", WithIPv4Only())
+	if err != nil || result.IPv4[0].String() != "5.6.7.8" {
+		t.Fatalf("ResolveSingle(IPv4Only) = %+v, %v, want new answer", result, err)
+	}
+
+	// 正常结果清除异常计数，之后的异常重新获得保护
+	seedExpiredEntry(resolver, []string{"1.2.3.4"}, nil)
+	setAnswer(HTTPDNSResponse{Host: "example.com", TTL: 60})
+	result, err = resolver.ResolveSingle(context.Background(), "example.com")
+	if err != nil || len(result.IPv4) != 1 {
+		t.Errorf("ResolveSingle() = %+v, %v, want last known good after recovery", result, err)
+	}
+}
+
+func TestResolver_LastKnownGood_Shrink(t *testing.T) {
+	server, setAnswer := newAnomalyTestServer(t)
+	setAnswer(HTTPDNSResponse{Host: "example.com", IPs: []string{"1.1.1.1"}, TTL: 60})
+
+	config := DefaultConfig()
+	config.AccountID = "test123"
+	config.BootstrapIPs = []string{server.URL[7:]}
+	config.EnableMetrics = true
+	config.LastKnownGoodAttempts = 1
+
+	resolver := NewResolver(config)
+	seedExpiredEntry(resolver, []string{"1.1.1.1", "2.2.2.2", "3.3.3.3", "4.4.4.4"}, nil)
+
+	// 地址数从 4 个骤减到 1 个视为部分结果，保留原有缓存
+	result, err := resolver.ResolveSingle(context.Background(), "example.com", WithIPv4Only())
+	if err != nil {
+		t.Fatalf("ResolveSingle() error = %v", err)
+	}
+	if len(result.IPv4) != 4 {
+		t.Errorf("ResolveSingle() IPv4 = %v, want last known good", result.IPv4)
+	}
+	if stats := resolver.GetMetrics(); stats.AnswersRejected != 1 {
+		t.Errorf("AnswersRejected = %d, want 1", stats.AnswersRejected)
+	}
+}
+
+func TestResolver_LastKnownGood_Refresh(t *testing.T) {
+	server, setAnswer := newAnomalyTestServer(t)
+
+	config := DefaultConfig()
+	config.AccountID = "test123"
+	config.BootstrapIPs = []string{server.URL[7:]}
+	config.EnableMetrics = true
+	config.LastKnownGoodAttempts = 3
+
+	resolver := NewResolver(config)
+	seedExpiredEntry(resolver, []string{"1.2.3.4"}, []string{"2001:db8::1"})
+
+	// 强制刷新不使用异常结果保护，部分结果直接覆盖缓存
+	setAnswer(HTTPDNSResponse{Host: "example.com", IPs: []string{"5.6.7.8"}, TTL: 60})
+	result, err := resolver.Refresh(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if len(result.IPv4) != 1 || result.IPv4[0].String() != "5.6.7.8" || len(result.IPv6) != 0 {
+		t.Errorf("Refresh() = %+v, want the server answer", result)
+	}
+
+	entry, ok := resolver.cacheManager.GetStale("example.com", -1)
+	if !ok || len(entry.IPv4) != 1 || entry.IPv4[0] != "5.6.7.8" || len(entry.IPv6) != 0 {
+		t.Errorf("cache entry = %+v, want the refreshed answer", entry)
+	}
+
+	stats := resolver.GetMetrics()
+	if stats.AnswerAnomalies != 0 || stats.AnswersRejected != 0 {
+		t.Errorf("AnswerAnomalies = %d, AnswersRejected = %d, want 0 for forced refresh", stats.AnswerAnomalies, stats.AnswersRejected)
+	}
+}
+
+func TestIsDegradedAnswer(t *testing.T) {
+	entry := &CacheEntry{IPv4: []string{"1.2.3.4"}, IPv6: []string{"2001:db8::1"}}
+	wide := &CacheEntry{IPv4: []string{"1.1.1.1", "2.2.2.2", "3.3.3.3", "4.4.4.4"}}
+	ipv4Only := &ResolveResult{IPv4: []net.IP{net.ParseIP("1.2.3.4")}}
+	full := &ResolveResult{IPv4: []net.IP{net.ParseIP("1.2.3.4")}, IPv6: []net.IP{net.ParseIP("2001:db8::1")}}
+	half := &ResolveResult{IPv4: []net.IP{net.ParseIP("1.1.1.1"), net.ParseIP("2.2.2.2")}}
+
+	tests := []struct {
+		name        string
+		entry       *CacheEntry
+		result      *ResolveResult
+		queryType   QueryType
+		shrinkRatio float64
+		want        bool
+	}{
+		{name: "empty answer", entry: entry, result: &ResolveResult{}, queryType: QueryBoth, want: true},
+		{name: "missing ipv6", entry: entry, result: ipv4Only, queryType: QueryBoth, want: true},
+		{name: "ipv4 only query", entry: entry, result: ipv4Only, queryType: QueryIPv4, want: false},
+		{name: "full answer", entry: entry, result: full, queryType: QueryBoth, want: false},
+		{name: "shrunk from 4 to 1", entry: wide, result: ipv4Only, queryType: QueryBoth, shrinkRatio: 0.5, want: true},
+		{name: "shrunk from 4 to 2", entry: wide, result: half, queryType: QueryBoth, shrinkRatio: 0.5, want: false},
+		{name: "shrink check disabled", entry: wide, result: ipv4Only, queryType: QueryBoth, shrinkRatio: 0, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isDegradedAnswer(tt.entry, tt.result, tt.queryType, tt.shrinkRatio); got != tt.want {
+				t.Errorf("isDegradedAnswer() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,7 +3,12 @@ package httpdns
 import (
 	"crypto/md5"
 	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 // generateSignature 生成签名算法实现
@@ -33,3 +38,89 @@ func generateBatchSignature(secretKey string, hosts []string, timestamp string)
 
 	return signature
 }
+
+// Credentials 鉴权凭据
+type Credentials struct {
+	AccountID string
+	SecretKey string // 为空时使用非鉴权解析
+}
+
+// CredentialProvider 凭据提供者，每次构建请求时调用，可在运行时轮换凭据而无需重建客户端
+// 实现需保证并发安全
+type CredentialProvider interface {
+	Credentials() (Credentials, error)
+}
+
+// StaticCredentials 固定凭据
+type StaticCredentials Credentials
+
+// Credentials 返回固定凭据
+func (c StaticCredentials) Credentials() (Credentials, error) {
+	return Credentials(c), nil
+}
+
+// RotatingCredentials 可在运行时轮换的凭据
+type RotatingCredentials struct {
+	mutex   sync.RWMutex
+	current Credentials
+}
+
+// NewRotatingCredentials 创建可轮换的凭据
+func NewRotatingCredentials(accountID, secretKey string) *RotatingCredentials {
+	return &RotatingCredentials{current: Credentials{AccountID: accountID, SecretKey: secretKey}}
+}
+
+// Credentials 返回当前凭据
+func (r *RotatingCredentials) Credentials() (Credentials, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.current, nil
+}
+
+// Rotate 更新凭据，之后构建的请求立即使用新凭据
+func (r *RotatingCredentials) Rotate(accountID, secretKey string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.current = Credentials{AccountID: accountID, SecretKey: secretKey}
+}
+
+// SignRequest 待签名的解析请求
+type SignRequest struct {
+	Hosts       []string    // 解析的域名，单域名解析时只有一个元素
+	Batch       bool        // 是否为批量解析
+	ExpireAt    time.Time   // 签名过期时间
+	Credentials Credentials // 当前凭据
+}
+
+// Signer 请求签名器，返回附加到解析URL上的签名参数
+// 默认的 MD5Signer 返回 t（过期时间戳）和 s（签名），其他签名方案（如基于HMAC）可返回各自需要的参数
+type Signer interface {
+	Sign(req *SignRequest) (url.Values, error)
+}
+
+// SignerFunc 函数形式的签名器
+type SignerFunc func(req *SignRequest) (url.Values, error)
+
+// Sign 调用函数签名
+func (f SignerFunc) Sign(req *SignRequest) (url.Values, error) {
+	return f(req)
+}
+
+// MD5Signer 默认签名器：MD5(host-secret-timestamp)
+type MD5Signer struct{}
+
+// Sign 生成 MD5 签名参数
+func (MD5Signer) Sign(req *SignRequest) (url.Values, error) {
+	if len(req.Hosts) == 0 {
+		return nil, fmt.Errorf("no host to sign")
+	}
+
+	timestamp := strconv.FormatInt(req.ExpireAt.Unix(), 10)
+	var signature string
+	if req.Batch {
+		signature = generateBatchSignature(req.Credentials.SecretKey, req.Hosts, timestamp)
+	} else {
+		signature = generateSignature(req.Credentials.SecretKey, req.Hosts[0], timestamp)
+	}
+	return url.Values{"t": {timestamp}, "s": {signature}}, nil
+}
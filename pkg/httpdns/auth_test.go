@@ -1,7 +1,15 @@
 package httpdns
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -196,3 +204,128 @@ func TestAuthManager_TimestampExpiration(t *testing.T) {
 		t.Errorf("Batch timestamp %v should be after current time %v", timestampTime, beforeTime)
 	}
 }
+
+func TestMD5Signer(t *testing.T) {
+	expireAt := time.Unix(1534316400, 0)
+	creds := Credentials{AccountID: "test123", SecretKey: "IAmASecret"}
+
+	params, err := MD5Signer{}.Sign(&SignRequest{Hosts: []string{"www.aliyun.com"}, ExpireAt: expireAt, Credentials: creds})
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	if params.Get("t") != "1534316400" || params.Get("s") != "60c71e98b6d7fcbb366243e224eab457" {
+		t.Errorf("Sign() = %v, want documented signature", params)
+	}
+
+	params, err = MD5Signer{}.Sign(&SignRequest{Hosts: []string{"www.aliyun.com", "www.taobao.com"}, Batch: true, ExpireAt: expireAt, Credentials: creds})
+	if err != nil || params.Get("s") != "12a3f6b1b14a46ca813ca6439beb59a4" {
+		t.Errorf("Sign() batch = %v, %v, want documented signature", params, err)
+	}
+
+	if _, err := (MD5Signer{}).Sign(&SignRequest{ExpireAt: expireAt, Credentials: creds}); err == nil {
+		t.Error("Sign() without hosts should fail")
+	}
+}
+
+func TestRequestBuilder_SignerAndCredentials(t *testing.T) {
+	config := &Config{AccountID: "test123"}
+	creds := NewRotatingCredentials("acct1", "secret1")
+	signer := SignerFunc(func(req *SignRequest) (url.Values, error) {
+		return url.Values{"sig": {req.Credentials.AccountID + ":" + req.Credentials.SecretKey}, "alg": {"hmac-sha256"}}, nil
+	})
+	builder := NewRequestBuilder(config, NewAuthManagerWithProvider(creds, signer, 30*time.Second))
+
+	got, err := builder.SingleResolveURL("203.107.1.1", "example.com", "", QueryIPv4)
+	if err != nil {
+		t.Fatalf("SingleResolveURL() error = %v", err)
+	}
+	if want := "http://203.107.1.1/acct1/sign_d?host=example.com&query=4&alg=hmac-sha256&sig=acct1%3Asecret1"; got != want {
+		t.Errorf("SingleResolveURL() = %s, want %s", got, want)
+	}
+
+	// 轮换后立即使用新凭据
+	creds.Rotate("acct2", "secret2")
+	got, err = builder.BatchResolveURL("203.107.1.1", []string{"a.com", "b.com"}, "", QueryIPv4)
+	if err != nil {
+		t.Fatalf("BatchResolveURL() error = %v", err)
+	}
+	if !strings.HasPrefix(got, "http://203.107.1.1/acct2/sign_resolve?") || !strings.Contains(got, "sig=acct2%3Asecret2") {
+		t.Errorf("BatchResolveURL() after rotation = %s", got)
+	}
+	if got := builder.BuildServiceIPURL("203.107.1.1"); got != "http://203.107.1.1/acct2/ss" {
+		t.Errorf("BuildServiceIPURL() after rotation = %s", got)
+	}
+
+	// 凭据中没有密钥时使用非鉴权解析
+	creds.Rotate("acct3", "")
+	if got, _ := builder.SingleResolveURL("203.107.1.1", "example.com", "", QueryIPv4); got != "http://203.107.1.1/acct3/d?host=example.com&query=4" {
+		t.Errorf("SingleResolveURL() without secret = %s", got)
+	}
+
+	// 签名失败时返回错误
+	failing := NewRequestBuilder(config, NewAuthManagerWithProvider(StaticCredentials{SecretKey: "secret"}, SignerFunc(func(*SignRequest) (url.Values, error) {
+		return nil, errors.New("signer unavailable")
+	}), 30*time.Second))
+	if _, err := failing.SingleResolveURL("203.107.1.1", "example.com", "", QueryIPv4); err == nil {
+		t.Error("SingleResolveURL() should fail when signer fails")
+	}
+	if got := failing.BuildSingleResolveURL("203.107.1.1", "example.com", "", QueryIPv4); got != "" {
+		t.Errorf("BuildSingleResolveURL() = %s, want empty on signer failure", got)
+	}
+}
+
+func TestClient_CredentialRotation(t *testing.T) {
+	var mu sync.Mutex
+	var accounts []string
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/ss") {
+			json.NewEncoder(w).Encode(map[string]interface{}{"service_ip": []string{server.URL[7:]}})
+			return
+		}
+
+		// 校验签名使用的是路径中账号对应的密钥
+		account := strings.Split(strings.Trim(r.URL.Path, "/"), "/")[0]
+		query := r.URL.Query()
+		if generateSignature("secret-"+account, query.Get("host"), query.Get("t")) != query.Get("s") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		mu.Lock()
+		accounts = append(accounts, account)
+		mu.Unlock()
+		json.NewEncoder(w).Encode(HTTPDNSResponse{Host: query.Get("host"), IPs: []string{"1.2.3.4"}, TTL: 60})
+	}))
+	defer server.Close()
+
+	creds := NewRotatingCredentials("acct1", "secret-acct1")
+	config := DefaultConfig()
+	config.CredentialProvider = creds
+	config.BootstrapIPs = []string{server.URL[7:]}
+	config.EnableMemoryCache = false
+
+	client, err := NewClient(config)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	defer client.Close()
+	if config.AccountID != "acct1" {
+		t.Errorf("AccountID = %s, want initial account from provider", config.AccountID)
+	}
+
+	ctx := context.Background()
+	if _, err := client.Resolve(ctx, "example.com"); err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	creds.Rotate("acct2", "secret-acct2")
+	if _, err := client.Resolve(ctx, "example.com"); err != nil {
+		t.Fatalf("Resolve() after rotation error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(accounts) != 2 || accounts[0] != "acct1" || accounts[1] != "acct2" {
+		t.Errorf("signed requests by account = %v, want [acct1 acct2]", accounts)
+	}
+}
@@ -1,12 +1,12 @@
 package httpdns
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"os"
 	"path/filepath"
-	"runtime"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -18,6 +18,9 @@ type CacheEntry struct {
 	IPv6      []string  `json:"ipv6"`       // IPv6地址列表
 	TTL       int       `json:"ttl"`        // TTL（秒）
 	QueryTime time.Time `json:"query_time"` // 查询时间
+
+	// ImportedAt 从快照导入的时间，多进程合并时与删除标记比较（为空时使用查询时间）
+	ImportedAt time.Time `json:"imported_at,omitempty"`
 }
 
 // normalizeDomain 规范化域名（去空格 + 转小写 + 去尾点）
@@ -34,6 +37,23 @@ func (e *CacheEntry) IsExpired() bool {
 	return time.Now().After(e.QueryTime.Add(time.Duration(e.TTL) * time.Second))
 }
 
+// Staleness 返回条目已过期的时长（未过期时返回0）
+func (e *CacheEntry) Staleness(now time.Time) time.Duration {
+	stale := now.Sub(e.QueryTime.Add(time.Duration(e.TTL) * time.Second))
+	if stale < 0 {
+		return 0
+	}
+	return stale
+}
+
+// writtenAt 记录写入缓存的时间，用于和删除标记比较：导入的记录为导入时间，其他为查询时间
+func (e *CacheEntry) writtenAt() time.Time {
+	if e.ImportedAt.After(e.QueryTime) {
+		return e.ImportedAt
+	}
+	return e.QueryTime
+}
+
 // IsPersistExpired 判断持久化缓存是否过期
 // 过期判断公式：当前时间 > 查询时间 + TTL + threshold
 func (e *CacheEntry) IsPersistExpired(threshold time.Duration) bool {
@@ -45,8 +65,10 @@ func (e *CacheEntry) ToResolveResult(domain string) *ResolveResult {
 	result := &ResolveResult{
 		Domain:    domain,
 		TTL:       time.Duration(e.TTL) * time.Second,
+		ExpiresAt: e.QueryTime.Add(time.Duration(e.TTL) * time.Second),
 		Timestamp: e.QueryTime,
 		Source:    SourceHTTPDNS,
+		Stale:     e.IsExpired(),
 	}
 
 	for _, ipStr := range e.IPv4 {
@@ -64,27 +86,77 @@ func (e *CacheEntry) ToResolveResult(domain string) *ResolveResult {
 	return result
 }
 
+// accessStats 缓存条目的访问统计
+type accessStats struct {
+	hits        int64     // 自上次写入以来的访问次数
+	total       int64     // 累计访问次数
+	lastAccess  time.Time // 最近访问时间
+	lastRefresh time.Time // 最近一次提前刷新的时间
+}
+
+// negativeEntry 负缓存条目
+type negativeEntry struct {
+	err      error     // 导致负缓存的原因（ErrNoAddresses 或最近一次解析错误）
+	failures int       // 连续失败次数，用于计算退避时长
+	until    time.Time // 负缓存到期时间
+}
+
+// refreshAheadRetryInterval 同一条目两次提前刷新之间的最小间隔（防止刷新失败时反复请求）
+const refreshAheadRetryInterval = 5 * time.Second
+
+// accessStatsRetention 持久化访问统计的保留时长，超过该时长未访问的域名不再保存
+const accessStatsRetention = 7 * 24 * time.Hour
+
+// maxAccessStats 访问统计的域名数上限，超出时清理保留期外和最久未访问的域名
+const maxAccessStats = 10000
+
+// tombstoneRetention 删除标记的保留时长，用于多进程合并时防止已删除的记录被其他进程写回
+const tombstoneRetention = 24 * time.Hour
 
 // CacheManager 统一缓存管理器（内存 + 持久化）
 type CacheManager struct {
 	// 内存缓存
 	cache      map[string]*CacheEntry
+	tombstones map[string]time.Time // 已删除域名及删除时间（由 cacheMutex 保护）
 	cacheMutex sync.RWMutex
 
+	// 访问统计
+	stats      map[string]*accessStats
+	statsMutex sync.Mutex
+
+	// 负缓存
+	negative       map[string]*negativeEntry
+	negativeMutex  sync.Mutex
+	negativeTTL    time.Duration
+	negativeMaxTTL time.Duration
+
 	// 配置
-	enabled      bool          // 是否启用内存缓存
-	allowExpired bool          // 是否允许使用过期缓存
-	persistent   bool          // 是否启用持久化
-	threshold    time.Duration // 持久化缓存过期阈值
+	enabled         bool          // 是否启用内存缓存
+	allowExpired    bool          // 是否允许使用过期缓存（不限时长）
+	maxStaleRevalid time.Duration // stale-while-revalidate 窗口
+	persistent      bool          // 是否启用持久化
+	threshold       time.Duration // 持久化缓存过期阈值
+
+	// 提前刷新配置
+	refreshAhead    bool
+	refreshFraction float64
+	refreshWindow   time.Duration
+	refreshMinHits  int64
 
 	// 持久化
-	cacheDir  string     // 缓存目录
-	fileMutex sync.Mutex // 文件写入锁
+	store   PersistentStore  // 持久化存储
+	journal JournalStore     // 存储支持增量写入时非空
+	metrics MetricsCollector // 记录持久化文件损坏（可为 nil）
 
 	// 异步保存控制（防止 goroutine 堆积）
-	saveMu      sync.Mutex
-	saving      bool // 是否正在保存
-	savePending bool // 是否有待处理的保存请求
+	saveMu           sync.Mutex
+	saving           bool                // 是否正在保存
+	savePending      bool                // 是否有待处理的保存请求
+	flushInterval    time.Duration       // 保存防抖间隔，间隔内的多次更新合并为一次写入
+	flushTimer       *time.Timer         // 等待中的防抖定时器
+	dirty            map[string]struct{} // 待写入日志的域名
+	journalRecords   int                 // 上次压缩后写入日志的记录数
+	compactThreshold int                 // 日志记录数达到该值时压缩为完整快照
 
 	logger Logger
 }
@@ -92,39 +164,62 @@ type CacheManager struct {
 // NewCacheManager 创建缓存管理器
 func NewCacheManager(config *Config) *CacheManager {
 	cm := &CacheManager{
-		cache:        make(map[string]*CacheEntry),
-		enabled:      config.EnableMemoryCache,
-		allowExpired: config.AllowExpiredCache,
-		persistent:   config.EnablePersistentCache,
-		threshold:    config.CacheExpireThreshold,
-		logger:       config.Logger,
+		cache:            make(map[string]*CacheEntry),
+		stats:            make(map[string]*accessStats),
+		negative:         make(map[string]*negativeEntry),
+		negativeTTL:      config.NegativeCacheTTL,
+		negativeMaxTTL:   config.NegativeCacheMaxTTL,
+		enabled:          config.EnableMemoryCache,
+		allowExpired:     config.AllowExpiredCache,
+		maxStaleRevalid:  config.MaxStaleWhileRevalidate,
+		persistent:       config.EnablePersistentCache,
+		threshold:        config.CacheExpireThreshold,
+		refreshAhead:     config.EnableRefreshAhead,
+		refreshFraction:  config.RefreshAheadFraction,
+		refreshWindow:    config.RefreshAheadWindow,
+		refreshMinHits:   config.RefreshAheadMinHits,
+		flushInterval:    config.PersistentFlushInterval,
+		compactThreshold: config.PersistentCompactThreshold,
+		logger:           config.Logger,
 	}
 
-	// 初始化持久化缓存目录
+	// 初始化持久化存储：优先使用自定义存储，否则使用缓存目录下的文件存储
 	if cm.persistent {
-		cacheDir, err := getCacheDir(config.AccountID)
-		if err != nil {
+		if config.PersistentStore != nil {
+			cm.store = config.PersistentStore
+		} else if store, err := newDefaultFileStore(config); err != nil {
 			if cm.logger != nil {
-				cm.logger.Printf("Failed to get cache directory: %v, persistent cache disabled", err)
+				cm.logger.Printf("Failed to initialize cache directory: %v, persistent cache disabled", err)
 			}
 			cm.persistent = false
 		} else {
-			cm.cacheDir = cacheDir
-			if err := ensureCacheDir(cacheDir); err != nil {
-				if cm.logger != nil {
-					cm.logger.Printf("Failed to create cache directory: %v, persistent cache disabled", err)
-				}
-				cm.persistent = false
-			}
+			cm.store = store
 		}
 	}
+	if journal, ok := cm.store.(JournalStore); ok {
+		cm.journal = journal
+	}
 
 	return cm
 }
 
-// Get 从内存缓存获取条目
+// Get 从内存缓存获取条目（使用配置的过期缓存策略）
 // 返回值：entry（缓存条目）, hit（是否命中）, needAsyncUpdate（是否需要异步更新）
 func (c *CacheManager) Get(domain string) (*CacheEntry, bool, bool) {
+	return c.Lookup(domain, c.DefaultMaxStale())
+}
+
+// DefaultMaxStale 返回配置的 stale-while-revalidate 窗口，-1 表示不限制
+func (c *CacheManager) DefaultMaxStale() time.Duration {
+	if c.allowExpired {
+		return -1
+	}
+	return c.maxStaleRevalid
+}
+
+// Lookup 从内存缓存获取条目，过期时长不超过 maxStale 的条目作为过期缓存返回（maxStale < 0 表示不限制）
+// 返回值：entry（缓存条目）, hit（是否命中）, needAsyncUpdate（是否需要异步更新）
+func (c *CacheManager) Lookup(domain string, maxStale time.Duration) (*CacheEntry, bool, bool) {
 	if !c.enabled {
 		return nil, false, false
 	}
@@ -139,19 +234,148 @@ func (c *CacheManager) Get(domain string) (*CacheEntry, bool, bool) {
 		return nil, false, false
 	}
 
+	stats := c.recordAccess(domain)
+	c.markAccessed(domain)
+
+	now := time.Now()
 	if entry.IsExpired() {
-		if c.allowExpired {
+		if maxStale < 0 || entry.Staleness(now) <= maxStale {
 			// 返回过期缓存，标记需要异步更新
 			return entry, true, true
 		}
-		// 缓存过期且不允许使用过期缓存
+		// 缓存过期且超出允许的过期时长
 		return nil, false, false
 	}
 
-	// 缓存命中且未过期
+	// 缓存命中且未过期，热点条目超过刷新阈值时标记需要异步更新（与定期扫描共用刷新间隔，刷新失败后不会立即重试）
+	if c.refreshAhead && c.isHot(stats, now) && c.pastRefreshPoint(entry, now) && c.claimRefresh(domain, now) {
+		return entry, true, true
+	}
 	return entry, true, false
 }
 
+// GetStale 获取过期时长不超过 maxStale 的条目（maxStale < 0 表示不限制），用于网络失败时兜底
+func (c *CacheManager) GetStale(domain string, maxStale time.Duration) (*CacheEntry, bool) {
+	if !c.enabled || maxStale == 0 {
+		return nil, false
+	}
+
+	domain = normalizeDomain(domain)
+
+	c.cacheMutex.RLock()
+	entry, exists := c.cache[domain]
+	c.cacheMutex.RUnlock()
+
+	if !exists || (maxStale > 0 && entry.Staleness(time.Now()) > maxStale) {
+		return nil, false
+	}
+	return entry, true
+}
+
+// recordAccess 记录一次访问，返回访问统计快照
+func (c *CacheManager) recordAccess(domain string) accessStats {
+	c.statsMutex.Lock()
+	defer c.statsMutex.Unlock()
+
+	if c.stats == nil {
+		c.stats = make(map[string]*accessStats)
+	}
+	now := time.Now()
+	stats, ok := c.stats[domain]
+	if !ok {
+		stats = &accessStats{}
+		c.stats[domain] = stats
+		c.pruneAccessStatsLocked(now)
+	}
+	stats.hits++
+	stats.total++
+	stats.lastAccess = now
+	return *stats
+}
+
+// pruneAccessStatsLocked 访问统计超过 maxAccessStats 时清理保留期外的域名，
+// 仍然超出时按最近访问时间淘汰到上限的 90%（调用方需持有 statsMutex）
+func (c *CacheManager) pruneAccessStatsLocked(now time.Time) {
+	if len(c.stats) <= maxAccessStats {
+		return
+	}
+
+	for domain, stats := range c.stats {
+		if now.Sub(stats.lastAccess) > accessStatsRetention {
+			delete(c.stats, domain)
+		}
+	}
+	if len(c.stats) <= maxAccessStats {
+		return
+	}
+
+	domains := make([]string, 0, len(c.stats))
+	for domain := range c.stats {
+		domains = append(domains, domain)
+	}
+	sort.Slice(domains, func(i, j int) bool {
+		return c.stats[domains[i]].lastAccess.Before(c.stats[domains[j]].lastAccess)
+	})
+	for _, domain := range domains[:len(domains)-maxAccessStats*9/10] {
+		delete(c.stats, domain)
+	}
+}
+
+// claimRefresh 距上次提前刷新超过 refreshAheadRetryInterval 时记录本次刷新并返回 true
+func (c *CacheManager) claimRefresh(domain string, now time.Time) bool {
+	c.statsMutex.Lock()
+	defer c.statsMutex.Unlock()
+
+	stats, ok := c.stats[domain]
+	if !ok {
+		return true
+	}
+	if now.Sub(stats.lastRefresh) < refreshAheadRetryInterval {
+		return false
+	}
+	stats.lastRefresh = now
+	return true
+}
+
+// isHot 判断条目是否为热点（窗口内被访问且访问次数达到阈值）
+func (c *CacheManager) isHot(stats accessStats, now time.Time) bool {
+	return stats.hits >= c.refreshMinHits && now.Sub(stats.lastAccess) <= c.refreshWindow
+}
+
+// pastRefreshPoint 判断条目存活时间是否已超过 TTL 的刷新比例
+func (c *CacheManager) pastRefreshPoint(entry *CacheEntry, now time.Time) bool {
+	ttl := time.Duration(entry.TTL) * time.Second
+	return now.Sub(entry.QueryTime) >= time.Duration(float64(ttl)*c.refreshFraction)
+}
+
+// RefreshAheadCandidates 返回需要提前刷新的热点域名，并记录刷新时间避免短时间内重复刷新
+func (c *CacheManager) RefreshAheadCandidates() []string {
+	if !c.enabled || !c.refreshAhead {
+		return nil
+	}
+
+	now := time.Now()
+
+	c.cacheMutex.RLock()
+	defer c.cacheMutex.RUnlock()
+	c.statsMutex.Lock()
+	defer c.statsMutex.Unlock()
+
+	var domains []string
+	for domain, stats := range c.stats {
+		entry, ok := c.cache[domain]
+		if !ok || !c.isHot(*stats, now) || !c.pastRefreshPoint(entry, now) {
+			continue
+		}
+		if now.Sub(stats.lastRefresh) < refreshAheadRetryInterval {
+			continue
+		}
+		stats.lastRefresh = now
+		domains = append(domains, domain)
+	}
+	return domains
+}
+
 // Set 设置内存缓存条目
 func (c *CacheManager) Set(domain string, entry *CacheEntry) {
 	if !c.enabled {
@@ -163,40 +387,323 @@ func (c *CacheManager) Set(domain string, entry *CacheEntry) {
 		if c.logger != nil {
 			c.logger.Printf("Invalid TTL %d for domain %s, using default 60s", entry.TTL, domain)
 		}
-		entry.TTL = 60
+		entry.TTL = defaultCacheTTL
 	}
 
 	domain = normalizeDomain(domain)
 
 	c.cacheMutex.Lock()
 	c.cache[domain] = entry
+	delete(c.tombstones, domain)
 	c.cacheMutex.Unlock()
+	c.markDirty(domain)
+
+	// 新条目重新开始统计本周期的访问次数，首次写入计为一次访问
+	c.statsMutex.Lock()
+	if stats, ok := c.stats[domain]; ok {
+		stats.hits = 0
+	} else {
+		if c.stats == nil {
+			c.stats = make(map[string]*accessStats)
+		}
+		now := time.Now()
+		c.stats[domain] = &accessStats{total: 1, lastAccess: now}
+		c.pruneAccessStatsLocked(now)
+	}
+	c.statsMutex.Unlock()
+
+	// 解析成功后清除负缓存
+	c.ClearNegative(domain)
 }
 
+// NegativeEnabled 是否启用负缓存
+func (c *CacheManager) NegativeEnabled() bool {
+	return c.enabled && c.negativeTTL > 0
+}
 
-// LoadFromDisk 从磁盘加载解析缓存到内存
-func (c *CacheManager) LoadFromDisk() error {
-	if !c.persistent || c.cacheDir == "" {
+// GetNegative 查询负缓存，命中时返回导致负缓存的错误，未命中返回 nil
+func (c *CacheManager) GetNegative(domain string) error {
+	if !c.NegativeEnabled() {
 		return nil
 	}
 
-	filePath := filepath.Join(c.cacheDir, "resolve_cache.json")
-	data, err := os.ReadFile(filePath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil // 文件不存在不是错误
+	domain = normalizeDomain(domain)
+
+	c.negativeMutex.Lock()
+	defer c.negativeMutex.Unlock()
+
+	entry, ok := c.negative[domain]
+	if !ok {
+		return nil
+	}
+
+	now := time.Now()
+	if now.Before(entry.until) {
+		return entry.err
+	}
+
+	// 过期较久的条目不再参与退避计算，直接清理
+	if now.Sub(entry.until) > c.negativeMaxTTL {
+		delete(c.negative, domain)
+	}
+	return nil
+}
+
+// SetNegative 记录一次空结果或解析失败，连续失败时负缓存时长指数增长（不超过 NegativeCacheMaxTTL）
+func (c *CacheManager) SetNegative(domain string, cause error) time.Duration {
+	if !c.NegativeEnabled() {
+		return 0
+	}
+
+	domain = normalizeDomain(domain)
+
+	c.negativeMutex.Lock()
+	defer c.negativeMutex.Unlock()
+
+	if c.negative == nil {
+		c.negative = make(map[string]*negativeEntry)
+	}
+	entry, ok := c.negative[domain]
+	if !ok {
+		entry = &negativeEntry{}
+		c.negative[domain] = entry
+	}
+
+	entry.failures++
+	entry.err = cause
+
+	ttl := c.negativeTTL
+	for i := 1; i < entry.failures && ttl < c.negativeMaxTTL; i++ {
+		ttl *= 2
+	}
+	if ttl > c.negativeMaxTTL {
+		ttl = c.negativeMaxTTL
+	}
+	entry.until = time.Now().Add(ttl)
+
+	return ttl
+}
+
+// ClearNegative 清除域名的负缓存
+func (c *CacheManager) ClearNegative(domain string) {
+	domain = normalizeDomain(domain)
+
+	c.negativeMutex.Lock()
+	delete(c.negative, domain)
+	c.negativeMutex.Unlock()
+}
+
+// NegativeCount 返回当前生效的负缓存条目数
+func (c *CacheManager) NegativeCount() int {
+	c.negativeMutex.Lock()
+	defer c.negativeMutex.Unlock()
+
+	now := time.Now()
+	count := 0
+	for _, entry := range c.negative {
+		if now.Before(entry.until) {
+			count++
 		}
-		return err
 	}
+	return count
+}
 
-	var cacheData ResolveCacheData
-	if err := json.Unmarshal(data, &cacheData); err != nil {
-		if c.logger != nil {
-			c.logger.Printf("Failed to parse resolve cache file: %v", err)
+
+// Domains 返回内存缓存中的全部域名（按字母序）
+func (c *CacheManager) Domains() []string {
+	c.cacheMutex.RLock()
+	domains := make([]string, 0, len(c.cache))
+	for domain := range c.cache {
+		domains = append(domains, domain)
+	}
+	c.cacheMutex.RUnlock()
+
+	sort.Strings(domains)
+	return domains
+}
+
+// Delete 删除域名的缓存、负缓存和访问统计，并同步到持久化缓存
+func (c *CacheManager) Delete(domain string) bool {
+	domain = normalizeDomain(domain)
+	return c.deleteMatching(func(d string) bool { return d == domain }) > 0
+}
+
+// DeleteSuffix 删除等于 suffix 或以 .suffix 结尾的所有域名，返回删除的缓存条目数
+func (c *CacheManager) DeleteSuffix(suffix string) int {
+	suffix = strings.TrimPrefix(normalizeDomain(suffix), "*.")
+	if suffix == "" {
+		return 0
+	}
+	return c.deleteMatching(func(d string) bool {
+		return d == suffix || strings.HasSuffix(d, "."+suffix)
+	})
+}
+
+// Flush 清空所有缓存、负缓存和访问统计，并同步到持久化缓存
+func (c *CacheManager) Flush() int {
+	return c.deleteMatching(func(string) bool { return true })
+}
+
+// deleteMatching 删除匹配的域名，有缓存条目被删除时触发持久化
+func (c *CacheManager) deleteMatching(match func(domain string) bool) int {
+	deleted := 0
+
+	now := time.Now()
+	c.cacheMutex.Lock()
+	for domain := range c.cache {
+		if match(domain) {
+			delete(c.cache, domain)
+			if c.tombstones == nil {
+				c.tombstones = make(map[string]time.Time)
+			}
+			c.tombstones[domain] = now
+			c.markDirty(domain)
+			deleted++
+		}
+	}
+	c.cacheMutex.Unlock()
+
+	c.statsMutex.Lock()
+	for domain := range c.stats {
+		if match(domain) {
+			delete(c.stats, domain)
+		}
+	}
+	c.statsMutex.Unlock()
+
+	c.negativeMutex.Lock()
+	for domain := range c.negative {
+		if match(domain) {
+			delete(c.negative, domain)
+		}
+	}
+	c.negativeMutex.Unlock()
+
+	if deleted > 0 {
+		c.SaveResolveCacheAsync()
+	}
+	return deleted
+}
+
+// accessStatsOf 返回域名的访问统计快照
+func (c *CacheManager) accessStatsOf(domain string) accessStats {
+	c.statsMutex.Lock()
+	defer c.statsMutex.Unlock()
+
+	if stats, ok := c.stats[domain]; ok {
+		return *stats
+	}
+	return accessStats{}
+}
+
+// WarmupCandidates 返回访问次数最多（其次按最近访问时间）的前 n 个域名中缓存缺失或已过期的域名
+func (c *CacheManager) WarmupCandidates(n int) []string {
+	if !c.enabled || n <= 0 {
+		return nil
+	}
+
+	type candidate struct {
+		domain string
+		stats  accessStats
+	}
+
+	c.statsMutex.Lock()
+	candidates := make([]candidate, 0, len(c.stats))
+	for domain, stats := range c.stats {
+		candidates = append(candidates, candidate{domain: domain, stats: *stats})
+	}
+	c.statsMutex.Unlock()
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].stats.total != candidates[j].stats.total {
+			return candidates[i].stats.total > candidates[j].stats.total
+		}
+		return candidates[i].stats.lastAccess.After(candidates[j].stats.lastAccess)
+	})
+	if len(candidates) > n {
+		candidates = candidates[:n]
+	}
+
+	c.cacheMutex.RLock()
+	defer c.cacheMutex.RUnlock()
+
+	domains := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		if entry, ok := c.cache[candidate.domain]; ok && !entry.IsExpired() {
+			continue
+		}
+		domains = append(domains, candidate.domain)
+	}
+	return domains
+}
+
+// snapshotAccessStats 复制保留期内的访问统计用于持久化
+func (c *CacheManager) snapshotAccessStats() map[string]*AccessStats {
+	now := time.Now()
+
+	c.statsMutex.Lock()
+	defer c.statsMutex.Unlock()
+
+	snapshot := make(map[string]*AccessStats, len(c.stats))
+	for domain, stats := range c.stats {
+		if stats.total == 0 || now.Sub(stats.lastAccess) > accessStatsRetention {
+			continue
 		}
-		return nil // 解析失败返回空缓存
+		snapshot[domain] = &AccessStats{Hits: stats.total, LastAccess: stats.lastAccess}
+	}
+	return snapshot
+}
+
+// restoreAccessStats 从持久化数据恢复访问统计
+func (c *CacheManager) restoreAccessStats(records map[string]*AccessStats) {
+	if len(records) == 0 {
+		return
+	}
+
+	now := time.Now()
+
+	c.statsMutex.Lock()
+	defer c.statsMutex.Unlock()
+
+	if c.stats == nil {
+		c.stats = make(map[string]*accessStats)
+	}
+	for domain, record := range records {
+		if record == nil || now.Sub(record.LastAccess) > accessStatsRetention {
+			continue
+		}
+		domain = normalizeDomain(domain)
+		stats, ok := c.stats[domain]
+		if !ok {
+			stats = &accessStats{}
+			c.stats[domain] = stats
+		}
+		stats.total += record.Hits
+		if record.LastAccess.After(stats.lastAccess) {
+			stats.lastAccess = record.LastAccess
+		}
+	}
+	c.pruneAccessStatsLocked(now)
+}
+
+// LoadFromDisk 从持久化存储加载解析缓存到内存
+func (c *CacheManager) LoadFromDisk() error {
+	if !c.persistent || c.store == nil {
+		return nil
+	}
+
+	cacheData, err := c.store.LoadResolveCache()
+	if err != nil {
+		c.recordLoadError(err)
+		return err
+	}
+	if cacheData == nil {
+		return nil // 数据不存在不是错误
 	}
 
+	// 恢复访问统计（包括缓存已过期的域名，用于启动预热）
+	c.restoreAccessStats(cacheData.Stats)
+
 	// 过滤过期记录并加载到内存
 	c.cacheMutex.Lock()
 	validCount := 0
@@ -206,6 +713,12 @@ func (c *CacheManager) LoadFromDisk() error {
 			c.cache[domain] = entry
 			validCount++
 		} else {
+			// 以过期记录的查询时间作为删除标记，重写时不会被合并回来，也不影响其他进程写入的新记录
+			if c.tombstones == nil {
+				c.tombstones = make(map[string]time.Time)
+			}
+			c.tombstones[domain] = entry.QueryTime
+			c.markDirty(domain)
 			expiredCount++
 		}
 	}
@@ -222,12 +735,112 @@ func (c *CacheManager) LoadFromDisk() error {
 	return nil
 }
 
-// SaveResolveCacheAsync 异步保存解析缓存到磁盘（防止 goroutine 堆积）
+// SyncFromStore 从持久化存储合并其他进程写入的记录，返回更新的条目数
+// 存储中查询时间更新的记录覆盖内存缓存，存储中较新的删除标记会删除内存中的旧记录
+func (c *CacheManager) SyncFromStore() (int, error) {
+	if !c.enabled || !c.persistent || c.store == nil {
+		return 0, nil
+	}
+
+	cacheData, err := c.store.LoadResolveCache()
+	if err != nil {
+		c.recordLoadError(err)
+		return 0, err
+	}
+	if cacheData == nil {
+		return 0, nil
+	}
+
+	updated := make([]string, 0)
+
+	c.cacheMutex.Lock()
+	for domain, deletedAt := range cacheData.Tombstones {
+		if entry, ok := c.cache[domain]; ok && !entry.writtenAt().After(deletedAt) {
+			delete(c.cache, domain)
+			updated = append(updated, domain)
+		}
+		if c.tombstones == nil {
+			c.tombstones = make(map[string]time.Time)
+		}
+		if deletedAt.After(c.tombstones[domain]) {
+			c.tombstones[domain] = deletedAt
+		}
+	}
+	for domain, entry := range cacheData.Records {
+		if entry == nil || entry.IsPersistExpired(c.threshold) {
+			continue
+		}
+		if deletedAt, ok := c.tombstones[domain]; ok && !entry.writtenAt().After(deletedAt) {
+			continue
+		}
+		if existing, ok := c.cache[domain]; ok && !entry.QueryTime.After(existing.QueryTime) {
+			continue
+		}
+		c.cache[domain] = entry
+		delete(c.tombstones, domain)
+		updated = append(updated, domain)
+	}
+	c.cacheMutex.Unlock()
+
+	// 其他进程写入了新记录，清除本地对应的负缓存
+	for _, domain := range updated {
+		c.ClearNegative(domain)
+	}
+	if len(updated) > 0 && c.logger != nil {
+		c.logger.Printf("Synced %d records from persistent cache", len(updated))
+	}
+	return len(updated), nil
+}
+
+// markDirty 记录待写入日志的域名（存储不支持增量写入时忽略）
+func (c *CacheManager) markDirty(domain string) {
+	if !c.persistent || c.journal == nil {
+		return
+	}
+
+	c.saveMu.Lock()
+	if c.dirty == nil {
+		c.dirty = make(map[string]struct{})
+	}
+	c.dirty[domain] = struct{}{}
+	c.saveMu.Unlock()
+}
+
+// markAccessed 使用增量日志时，将域名的访问统计随日志按写入防抖间隔保存，进程异常退出时不丢失
+func (c *CacheManager) markAccessed(domain string) {
+	if !c.persistent || c.journal == nil {
+		return
+	}
+	c.markDirty(domain)
+	c.SaveResolveCacheAsync()
+}
+
+// SaveResolveCacheAsync 异步保存解析缓存到持久化存储（防止 goroutine 堆积）
+// 配置了 flushInterval 时，间隔内的多次调用合并为一次写入
 func (c *CacheManager) SaveResolveCacheAsync() {
-	if !c.persistent || c.cacheDir == "" {
+	if !c.persistent || c.store == nil {
 		return
 	}
 
+	if c.flushInterval <= 0 {
+		c.startSave()
+		return
+	}
+
+	c.saveMu.Lock()
+	if c.flushTimer == nil {
+		c.flushTimer = time.AfterFunc(c.flushInterval, func() {
+			c.saveMu.Lock()
+			c.flushTimer = nil
+			c.saveMu.Unlock()
+			c.startSave()
+		})
+	}
+	c.saveMu.Unlock()
+}
+
+// startSave 启动后台保存，已有保存在进行中时合并请求
+func (c *CacheManager) startSave() {
 	c.saveMu.Lock()
 	if c.saving {
 		// 已有保存在进行中，合并请求
@@ -240,7 +853,7 @@ func (c *CacheManager) SaveResolveCacheAsync() {
 
 	go func() {
 		for {
-			c.doSaveResolveCache()
+			c.persist()
 
 			c.saveMu.Lock()
 			if c.savePending {
@@ -256,51 +869,145 @@ func (c *CacheManager) SaveResolveCacheAsync() {
 	}()
 }
 
+// SavePending 立即同步写入尚未保存的更新（客户端关闭时调用）
+// 使用增量日志时同时压缩为完整快照
+func (c *CacheManager) SavePending() {
+	if !c.persistent || c.store == nil {
+		return
+	}
+
+	c.saveMu.Lock()
+	pending := c.flushTimer != nil || len(c.dirty) > 0 || c.journalRecords > 0
+	if c.flushTimer != nil {
+		c.flushTimer.Stop()
+		c.flushTimer = nil
+	}
+	c.dirty = nil
+	c.journalRecords = 0
+	c.saveMu.Unlock()
+
+	if pending {
+		c.doSaveResolveCache()
+	}
+}
+
+// persist 执行一次保存：存储支持增量写入时追加日志，日志过长时压缩，否则重写完整快照
+func (c *CacheManager) persist() {
+	if c.journal == nil {
+		c.doSaveResolveCache()
+		return
+	}
+
+	c.saveMu.Lock()
+	dirty := c.dirty
+	c.dirty = nil
+	c.saveMu.Unlock()
+
+	if len(dirty) > 0 {
+		if err := c.journal.AppendResolveCache(c.journalRecordsOf(dirty)); err != nil {
+			if c.logger != nil {
+				c.logger.Printf("Failed to append resolve cache journal: %v, rewriting snapshot", err)
+			}
+			c.compact()
+			return
+		}
+	}
+
+	c.saveMu.Lock()
+	c.journalRecords += len(dirty)
+	needCompact := c.compactThreshold > 0 && c.journalRecords >= c.compactThreshold
+	c.saveMu.Unlock()
+
+	if needCompact {
+		c.compact()
+	}
+}
+
+// compact 将日志压缩为完整快照
+func (c *CacheManager) compact() {
+	c.saveMu.Lock()
+	c.journalRecords = 0
+	c.saveMu.Unlock()
+
+	c.doSaveResolveCache()
+}
+
+// journalRecordsOf 根据当前缓存和访问统计生成待写入的日志记录
+func (c *CacheManager) journalRecordsOf(domains map[string]struct{}) []JournalRecord {
+	now := time.Now()
+	records := make([]JournalRecord, 0, len(domains))
+
+	c.cacheMutex.RLock()
+	for domain := range domains {
+		if entry, ok := c.cache[domain]; ok {
+			records = append(records, JournalRecord{Domain: domain, Entry: entry, Time: now})
+		} else if deletedAt, ok := c.tombstones[domain]; ok {
+			records = append(records, JournalRecord{Domain: domain, Time: deletedAt})
+		}
+	}
+	c.cacheMutex.RUnlock()
+
+	c.statsMutex.Lock()
+	defer c.statsMutex.Unlock()
+	for i := range records {
+		if stats, ok := c.stats[records[i].Domain]; ok && records[i].Entry != nil && stats.total > 0 {
+			records[i].Stats = &AccessStats{Hits: stats.total, LastAccess: stats.lastAccess}
+		}
+	}
+	return records
+}
+
 // doSaveResolveCache 实际执行保存解析缓存的逻辑
 func (c *CacheManager) doSaveResolveCache() {
-	// 复制当前缓存
-	c.cacheMutex.RLock()
+	// 复制当前缓存和保留期内的删除标记
+	now := time.Now()
+	c.cacheMutex.Lock()
 	cacheCopy := make(map[string]*CacheEntry, len(c.cache))
 	for k, v := range c.cache {
 		cacheCopy[k] = v
 	}
-	c.cacheMutex.RUnlock()
+	tombstones := make(map[string]time.Time, len(c.tombstones))
+	for domain, deletedAt := range c.tombstones {
+		if now.Sub(deletedAt) > tombstoneRetention {
+			delete(c.tombstones, domain)
+			continue
+		}
+		tombstones[domain] = deletedAt
+	}
+	c.cacheMutex.Unlock()
 
-	c.fileMutex.Lock()
-	defer c.fileMutex.Unlock()
+	cacheData := &ResolveCacheData{Records: cacheCopy, Stats: c.snapshotAccessStats(), Tombstones: tombstones}
 
-	cacheData := ResolveCacheData{Records: cacheCopy}
-	if err := c.writeJSONFile("resolve_cache.json", cacheData); err != nil {
-		if c.logger != nil {
-			c.logger.Printf("Failed to save resolve cache: %v", err)
-		}
+	// 缓存已清空且没有需要同步给其他进程的删除标记时删除持久化数据
+	var err error
+	if len(cacheData.Records) == 0 && len(cacheData.Stats) == 0 && len(cacheData.Tombstones) == 0 {
+		err = c.store.DeleteResolveCache()
+	} else {
+		err = c.store.SaveResolveCache(cacheData)
+	}
+	if err != nil && c.logger != nil {
+		c.logger.Printf("Failed to save resolve cache: %v", err)
 	}
 }
 
-
-// LoadServiceIPs 从磁盘加载服务IP缓存
+// LoadServiceIPs 从持久化存储加载服务IP缓存
 // 返回值：IPs列表, 更新时间, 错误
 func (c *CacheManager) LoadServiceIPs() ([]string, time.Time, error) {
-	if !c.persistent || c.cacheDir == "" {
+	if !c.persistent || c.store == nil {
 		return nil, time.Time{}, nil
 	}
 
-	filePath := filepath.Join(c.cacheDir, "service_ips.json")
-	data, err := os.ReadFile(filePath)
+	ipData, err := c.store.LoadServiceIPs()
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, time.Time{}, nil
-		}
-		return nil, time.Time{}, err
-	}
-
-	var ipData ServiceIPCacheData
-	if err := json.Unmarshal(data, &ipData); err != nil {
+		c.recordLoadError(err)
 		if c.logger != nil {
-			c.logger.Printf("Failed to parse service IP cache file: %v", err)
+			c.logger.Printf("Failed to load service IP cache: %v", err)
 		}
 		return nil, time.Time{}, nil
 	}
+	if ipData == nil {
+		return nil, time.Time{}, nil
+	}
 
 	// 检查是否过期（24小时）
 	if time.Since(ipData.UpdatedAt) > 24*time.Hour {
@@ -310,21 +1017,25 @@ func (c *CacheManager) LoadServiceIPs() ([]string, time.Time, error) {
 	return ipData.IPs, ipData.UpdatedAt, nil
 }
 
-// SaveServiceIPsAsync 异步保存服务IP到磁盘
+// recordLoadError 持久化文件损坏时计入指标
+func (c *CacheManager) recordLoadError(err error) {
+	if c.metrics != nil && errors.Is(err, ErrCorruptCache) {
+		recordCacheCorruption(c.metrics)
+	}
+}
+
+// SaveServiceIPsAsync 异步保存服务IP到持久化存储
 func (c *CacheManager) SaveServiceIPsAsync(ips []string) {
-	if !c.persistent || c.cacheDir == "" {
+	if !c.persistent || c.store == nil {
 		return
 	}
 
 	go func() {
-		c.fileMutex.Lock()
-		defer c.fileMutex.Unlock()
-
-		ipData := ServiceIPCacheData{
+		ipData := &ServiceIPCacheData{
 			IPs:       ips,
 			UpdatedAt: time.Now(),
 		}
-		if err := c.writeJSONFile("service_ips.json", ipData); err != nil {
+		if err := c.store.SaveServiceIPs(ipData); err != nil {
 			if c.logger != nil {
 				c.logger.Printf("Failed to save service IPs: %v", err)
 			}
@@ -332,43 +1043,99 @@ func (c *CacheManager) SaveServiceIPsAsync(ips []string) {
 	}()
 }
 
+// ServiceIPCacheData 服务IP缓存数据
+type ServiceIPCacheData struct {
+	IPs       []string  `json:"ips"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
 
-// writeJSONFile 原子性写入JSON文件
-func (c *CacheManager) writeJSONFile(filename string, data interface{}) error {
-	filePath := filepath.Join(c.cacheDir, filename)
+// ResolveCacheData 解析结果缓存数据
+type ResolveCacheData struct {
+	Records    map[string]*CacheEntry  `json:"records"`
+	Stats      map[string]*AccessStats `json:"stats,omitempty"`      // 域名访问统计，用于启动预热
+	Tombstones map[string]time.Time    `json:"tombstones,omitempty"` // 已删除域名及删除时间，用于多进程合并
+}
 
-	// 序列化为紧凑JSON
-	jsonData, err := json.Marshal(data)
-	if err != nil {
-		return err
+// mergeResolveCacheData 合并两份解析缓存数据（不修改入参）
+// 同一域名保留查询时间较新的记录，早于删除标记的记录被丢弃，访问统计取较大值
+func mergeResolveCacheData(a, b *ResolveCacheData) *ResolveCacheData {
+	now := time.Now()
+	merged := &ResolveCacheData{
+		Records:    make(map[string]*CacheEntry, len(a.Records)+len(b.Records)),
+		Stats:      make(map[string]*AccessStats, len(a.Stats)+len(b.Stats)),
+		Tombstones: make(map[string]time.Time, len(a.Tombstones)+len(b.Tombstones)),
 	}
 
-	// Windows：直接覆盖写入
-	if runtime.GOOS == "windows" {
-		return os.WriteFile(filePath, jsonData, 0600)
+	for _, data := range []*ResolveCacheData{a, b} {
+		for domain, deletedAt := range data.Tombstones {
+			if now.Sub(deletedAt) <= tombstoneRetention && deletedAt.After(merged.Tombstones[domain]) {
+				merged.Tombstones[domain] = deletedAt
+			}
+		}
 	}
 
-	// 非 Windows：使用临时文件 + 原子重命名
-	tempPath := filePath + ".tmp"
-
-	// 写入临时文件
-	if err := os.WriteFile(tempPath, jsonData, 0600); err != nil {
-		return err
+	for _, data := range []*ResolveCacheData{a, b} {
+		for domain, entry := range data.Records {
+			if entry == nil {
+				continue
+			}
+			if deletedAt, ok := merged.Tombstones[domain]; ok && !entry.writtenAt().After(deletedAt) {
+				continue
+			}
+			if existing, ok := merged.Records[domain]; !ok || entry.QueryTime.After(existing.QueryTime) {
+				merged.Records[domain] = entry
+			}
+		}
+		for domain, stats := range data.Stats {
+			if stats == nil {
+				continue
+			}
+			if deletedAt, ok := merged.Tombstones[domain]; ok && !stats.LastAccess.After(deletedAt) {
+				continue
+			}
+			existing, ok := merged.Stats[domain]
+			if !ok {
+				merged.Stats[domain] = &AccessStats{Hits: stats.Hits, LastAccess: stats.LastAccess}
+				continue
+			}
+			if stats.Hits > existing.Hits {
+				existing.Hits = stats.Hits
+			}
+			if stats.LastAccess.After(existing.LastAccess) {
+				existing.LastAccess = stats.LastAccess
+			}
+		}
 	}
 
-	// 原子性重命名
-	return os.Rename(tempPath, filePath)
+	// 已写回新记录的域名不再需要删除标记
+	for domain := range merged.Records {
+		delete(merged.Tombstones, domain)
+	}
+	return merged
 }
 
-// ServiceIPCacheData 服务IP缓存数据
-type ServiceIPCacheData struct {
-	IPs       []string  `json:"ips"`
-	UpdatedAt time.Time `json:"updated_at"`
+// AccessStats 持久化的域名访问统计
+type AccessStats struct {
+	Hits       int64     `json:"hits"`        // 累计访问次数
+	LastAccess time.Time `json:"last_access"` // 最近访问时间
 }
 
-// ResolveCacheData 解析结果缓存数据
-type ResolveCacheData struct {
-	Records map[string]*CacheEntry `json:"records"`
+// newDefaultFileStore 根据配置创建默认的文件存储
+// 目录为 Config.CacheDir/<AccountID>，未配置时为 os.UserCacheDir()/alicloud_httpdns/<AccountID>
+func newDefaultFileStore(config *Config) (*FileStore, error) {
+	dir := ""
+	if config.CacheDir != "" {
+		dir = filepath.Join(config.CacheDir, config.AccountID)
+	} else {
+		var err error
+		if dir, err = getCacheDir(config.AccountID); err != nil {
+			return nil, err
+		}
+	}
+	if keys := config.cacheKeyProvider(); keys != nil {
+		return NewEncryptedFileStore(dir, config.AccountID, keys, config.Logger)
+	}
+	return NewAccountFileStore(dir, config.AccountID, config.Logger)
 }
 
 // getCacheDir 获取平台特定的缓存目录
@@ -0,0 +1,137 @@
+package httpdns
+
+import (
+	"context"
+	"time"
+)
+
+// CacheAdmin 缓存管理接口，用于排查和处理线上问题（如清除被污染的域名）
+type CacheAdmin interface {
+	// List 列出所有缓存条目（按域名排序）
+	List() []CacheEntryInfo
+
+	// Get 获取单个域名的缓存条目
+	Get(domain string) (CacheEntryInfo, bool)
+
+	// Delete 删除域名的缓存（包括负缓存和持久化缓存）
+	Delete(domain string) bool
+
+	// DeleteSuffix 删除等于 suffix 或为其子域名的所有缓存，返回删除的条目数
+	DeleteSuffix(suffix string) int
+
+	// Flush 清空所有缓存，返回删除的条目数
+	Flush() int
+
+	// Refresh 忽略缓存、负缓存和异常结果保护，立即通过网络重新解析域名并更新缓存
+	Refresh(ctx context.Context, domain string, opts ...ResolveOption) (*ResolveResult, error)
+}
+
+// CacheEntryInfo 缓存条目信息
+type CacheEntryInfo struct {
+	Domain     string        `json:"domain"`
+	IPv4       []string      `json:"ipv4"`
+	IPv6       []string      `json:"ipv6"`
+	TTL        time.Duration `json:"ttl"`
+	Remaining  time.Duration `json:"remaining"` // 剩余TTL，已过期时为负数
+	QueryTime  time.Time     `json:"query_time"`
+	ExpiresAt  time.Time     `json:"expires_at"`
+	Expired    bool          `json:"expired"`
+	Hits       int64         `json:"hits"` // 累计访问次数
+	LastAccess time.Time     `json:"last_access"`
+}
+
+// cacheAdmin CacheAdmin 的实现
+type cacheAdmin struct {
+	client *client
+}
+
+// Cache 返回缓存管理接口
+func (c *client) Cache() CacheAdmin {
+	return &cacheAdmin{client: c}
+}
+
+// List 列出所有缓存条目
+func (a *cacheAdmin) List() []CacheEntryInfo {
+	cm := a.client.cacheManager
+	domains := cm.Domains()
+
+	infos := make([]CacheEntryInfo, 0, len(domains))
+	for _, domain := range domains {
+		if info, ok := a.Get(domain); ok {
+			infos = append(infos, info)
+		}
+	}
+	return infos
+}
+
+// Get 获取单个域名的缓存条目
+func (a *cacheAdmin) Get(domain string) (CacheEntryInfo, bool) {
+	cm := a.client.cacheManager
+	domain = normalizeDomain(domain)
+
+	// 直接读取条目，不计入访问统计
+	entry, ok := cm.GetStale(domain, -1)
+	if !ok {
+		return CacheEntryInfo{}, false
+	}
+
+	ttl := time.Duration(entry.TTL) * time.Second
+	expiresAt := entry.QueryTime.Add(ttl)
+	stats := cm.accessStatsOf(domain)
+
+	return CacheEntryInfo{
+		Domain:     domain,
+		IPv4:       append([]string(nil), entry.IPv4...),
+		IPv6:       append([]string(nil), entry.IPv6...),
+		TTL:        ttl,
+		Remaining:  time.Until(expiresAt),
+		QueryTime:  entry.QueryTime,
+		ExpiresAt:  expiresAt,
+		Expired:    entry.IsExpired(),
+		Hits:       stats.total,
+		LastAccess: stats.lastAccess,
+	}, true
+}
+
+// Delete 删除域名的缓存
+func (a *cacheAdmin) Delete(domain string) bool {
+	domain = normalizeDomain(domain)
+	deleted := a.client.cacheManager.Delete(domain)
+
+	if deleted && a.client.config.Logger != nil {
+		a.client.config.Logger.Printf("Cache entry deleted by admin: %s", domain)
+	}
+	return deleted
+}
+
+// DeleteSuffix 删除等于 suffix 或为其子域名的所有缓存
+func (a *cacheAdmin) DeleteSuffix(suffix string) int {
+	deleted := a.client.cacheManager.DeleteSuffix(suffix)
+
+	if deleted > 0 && a.client.config.Logger != nil {
+		a.client.config.Logger.Printf("%d cache entries deleted by admin for suffix %s", deleted, suffix)
+	}
+	return deleted
+}
+
+// Flush 清空所有缓存
+func (a *cacheAdmin) Flush() int {
+	deleted := a.client.cacheManager.Flush()
+
+	if a.client.config.Logger != nil {
+		a.client.config.Logger.Printf("Cache flushed by admin: %d entries deleted", deleted)
+	}
+	return deleted
+}
+
+// Refresh 立即通过网络重新解析域名
+func (a *cacheAdmin) Refresh(ctx context.Context, domain string, opts ...ResolveOption) (*ResolveResult, error) {
+	a.client.mutex.RLock()
+	defer a.client.mutex.RUnlock()
+
+	if !a.client.started {
+		return nil, NewHTTPDNSError("client_stopped", domain, ErrServiceUnavailable)
+	}
+
+	return a.client.resolver.Refresh(ctx, domain, opts...)
+}
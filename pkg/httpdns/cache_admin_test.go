@@ -0,0 +1,157 @@
+package httpdns
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_CacheAdmin(t *testing.T) {
+	var requestCount int32
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/test123/ss" {
+			serverAddr := server.URL[7:]
+			response := map[string]interface{}{
+				"service_ip": []string{serverAddr},
+			}
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		count := atomic.AddInt32(&requestCount, 1)
+		ip := "1.2.3.4"
+		if count > 4 {
+			ip = "5.6.7.8"
+		}
+		response := HTTPDNSResponse{Host: r.URL.Query().Get("host"), IPs: []string{ip}, TTL: 60}
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	config := DefaultConfig()
+	config.AccountID = "test123"
+	config.BootstrapIPs = []string{server.URL[7:]}
+
+	client, err := NewClient(config)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	for _, domain := range []string{"example.com", "www.example.com", "api.example.com", "other.org"} {
+		if _, err := client.Resolve(ctx, domain); err != nil {
+			t.Fatalf("Resolve(%s) error = %v", domain, err)
+		}
+	}
+	client.Resolve(ctx, "other.org")
+
+	admin := client.Cache()
+
+	entries := admin.List()
+	if len(entries) != 4 || entries[0].Domain != "api.example.com" {
+		t.Fatalf("List() = %+v, want 4 entries sorted by domain", entries)
+	}
+
+	info, ok := admin.Get("OTHER.org.")
+	if !ok {
+		t.Fatal("Get() should find other.org")
+	}
+	if info.TTL != time.Minute || info.Remaining <= 0 || info.Remaining > time.Minute || info.Expired {
+		t.Errorf("Get() TTL = %v, Remaining = %v, Expired = %v", info.TTL, info.Remaining, info.Expired)
+	}
+	if info.Hits != 2 {
+		t.Errorf("Get() Hits = %d, want 2", info.Hits)
+	}
+
+	if !admin.Delete("other.org") || admin.Delete("other.org") {
+		t.Error("Delete() should delete the entry exactly once")
+	}
+	if got := admin.DeleteSuffix("www.example.com"); got != 1 {
+		t.Errorf("DeleteSuffix(www.example.com) = %d, want 1", got)
+	}
+	if got := admin.DeleteSuffix("*.example.com"); got != 2 {
+		t.Errorf("DeleteSuffix(*.example.com) = %d, want 2", got)
+	}
+	if got := len(admin.List()); got != 0 {
+		t.Errorf("List() after deletions = %d entries, want 0", got)
+	}
+
+	// 强制刷新绕过缓存
+	if _, err := client.Resolve(ctx, "example.com"); err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	result, err := admin.Refresh(ctx, "example.com")
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if result.IPv4[0].String() != "5.6.7.8" {
+		t.Errorf("Refresh() IPv4 = %v, want fresh answer", result.IPv4)
+	}
+	if info, _ := admin.Get("example.com"); len(info.IPv4) != 1 || info.IPv4[0] != "5.6.7.8" {
+		t.Errorf("Get() after Refresh = %+v, want updated entry", info)
+	}
+
+	if got := admin.Flush(); got != 1 {
+		t.Errorf("Flush() = %d, want 1", got)
+	}
+
+	if _, err := admin.Refresh(ctx, ""); err == nil {
+		t.Error("Refresh() with empty domain should fail")
+	}
+
+	client.Close()
+	if _, err := admin.Refresh(ctx, "example.com"); err == nil {
+		t.Error("Refresh() after Close should fail")
+	}
+}
+
+func TestCacheManager_DeletePersists(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "httpdns_cache_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	cm := &CacheManager{
+		cache:      make(map[string]*CacheEntry),
+		enabled:    true,
+		persistent: true,
+		store:      &FileStore{dir: tempDir},
+		threshold:  0,
+	}
+
+	cm.Set("example.com", &CacheEntry{IPv4: []string{"1.2.3.4"}, TTL: 60, QueryTime: time.Now()})
+	cm.Set("poisoned.com", &CacheEntry{IPv4: []string{"6.6.6.6"}, TTL: 60, QueryTime: time.Now()})
+	cm.doSaveResolveCache()
+
+	if !cm.Delete("poisoned.com") {
+		t.Fatal("Delete() should return true")
+	}
+
+	// 删除后异步重写持久化文件
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		persisted, err := cm.store.LoadResolveCache()
+		if err == nil && persisted != nil && persisted.Records["poisoned.com"] == nil {
+			if persisted.Records["example.com"] == nil {
+				t.Errorf("persisted cache lost example.com: %+v", persisted.Records)
+			}
+			if persisted.Stats["poisoned.com"] != nil {
+				t.Errorf("persisted cache still has stats for deleted domain: %+v", persisted.Stats)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("persisted cache still contains deleted domain: %+v, %v", persisted, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -2,6 +2,8 @@ package httpdns
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -267,7 +269,7 @@ func TestCacheManager_Persistence(t *testing.T) {
 		cache:      make(map[string]*CacheEntry),
 		enabled:    true,
 		persistent: true,
-		cacheDir:   tempDir,
+		store:      &FileStore{dir: tempDir},
 		threshold:  0,
 	}
 
@@ -280,12 +282,9 @@ func TestCacheManager_Persistence(t *testing.T) {
 	cm.Set("example.com", entry)
 
 	// 同步保存（测试用）
-	cm.fileMutex.Lock()
 	cacheData := ResolveCacheData{Records: cm.cache}
-	err = cm.writeJSONFile("resolve_cache.json", cacheData)
-	cm.fileMutex.Unlock()
-	if err != nil {
-		t.Fatalf("writeJSONFile() error = %v", err)
+	if err := cm.store.SaveResolveCache(&cacheData); err != nil {
+		t.Fatalf("SaveResolveCache() error = %v", err)
 	}
 
 	// 验证文件存在
@@ -299,7 +298,7 @@ func TestCacheManager_Persistence(t *testing.T) {
 		cache:      make(map[string]*CacheEntry),
 		enabled:    true,
 		persistent: true,
-		cacheDir:   tempDir,
+		store:      &FileStore{dir: tempDir},
 		threshold:  0,
 	}
 
@@ -492,7 +491,7 @@ func TestCacheManager_LoadFromDisk_ExpiredRecords(t *testing.T) {
 		cache:      make(map[string]*CacheEntry),
 		enabled:    true,
 		persistent: true,
-		cacheDir:   tempDir,
+		store:      &FileStore{dir: tempDir},
 		threshold:  0,
 	}
 
@@ -522,14 +521,9 @@ func TestCacheManager_LoadFromDisk_ExpiredRecords(t *testing.T) {
 	time.Sleep(200 * time.Millisecond)
 
 	// 验证磁盘文件已更新（过期记录被删除）
-	data, err = os.ReadFile(cacheFile)
-	if err != nil {
-		t.Fatalf("Failed to read cache file: %v", err)
-	}
-
-	var reloadedData ResolveCacheData
-	if err := json.Unmarshal(data, &reloadedData); err != nil {
-		t.Fatalf("Failed to unmarshal cache data: %v", err)
+	reloadedData, err := cm.store.LoadResolveCache()
+	if err != nil || reloadedData == nil {
+		t.Fatalf("Failed to reload cache data: %v, %v", reloadedData, err)
 	}
 
 	if _, exists := reloadedData.Records["expired.com"]; exists {
@@ -539,3 +533,292 @@ func TestCacheManager_LoadFromDisk_ExpiredRecords(t *testing.T) {
 		t.Error("valid.com should remain in disk cache")
 	}
 }
+
+// TestCacheManager_RefreshAhead 测试热点条目超过TTL比例后标记提前刷新
+func TestCacheManager_RefreshAhead(t *testing.T) {
+	config := DefaultConfig()
+	config.AccountID = "test123"
+	config.EnableRefreshAhead = true
+	config.RefreshAheadFraction = 0.75
+	config.RefreshAheadMinHits = 2
+
+	cm := NewCacheManager(config)
+
+	// 已存活 80 秒（TTL 100 秒），超过 75% 阈值但未过期
+	cm.Set("hot.com", &CacheEntry{
+		IPv4:      []string{"1.2.3.4"},
+		TTL:       100,
+		QueryTime: time.Now().Add(-80 * time.Second),
+	})
+	// 仅存活 10 秒
+	cm.Set("fresh.com", &CacheEntry{
+		IPv4:      []string{"5.6.7.8"},
+		TTL:       100,
+		QueryTime: time.Now().Add(-10 * time.Second),
+	})
+
+	// 第一次访问未达到最少访问次数
+	if _, hit, needAsync := cm.Get("hot.com"); !hit || needAsync {
+		t.Errorf("Get() first access hit=%v needAsync=%v, want hit without refresh", hit, needAsync)
+	}
+	// 第二次访问成为热点，触发提前刷新
+	if _, hit, needAsync := cm.Get("hot.com"); !hit || !needAsync {
+		t.Errorf("Get() hot access hit=%v needAsync=%v, want hit with refresh", hit, needAsync)
+	}
+	// 刷新间隔内（如刷新失败后）再次访问不重复触发
+	if _, hit, needAsync := cm.Get("hot.com"); !hit || needAsync {
+		t.Errorf("Get() within retry interval hit=%v needAsync=%v, want hit without refresh", hit, needAsync)
+	}
+	if candidates := cm.RefreshAheadCandidates(); len(candidates) != 0 {
+		t.Errorf("RefreshAheadCandidates() within retry interval = %v, want empty", candidates)
+	}
+
+	cm.Get("fresh.com")
+	cm.Get("fresh.com")
+
+	// 超过刷新间隔后定期扫描返回热点条目
+	cm.stats["hot.com"].lastRefresh = time.Now().Add(-refreshAheadRetryInterval)
+	candidates := cm.RefreshAheadCandidates()
+	if len(candidates) != 1 || candidates[0] != "hot.com" {
+		t.Errorf("RefreshAheadCandidates() = %v, want [hot.com]", candidates)
+	}
+
+	// 刷新间隔内不会重复返回
+	if candidates := cm.RefreshAheadCandidates(); len(candidates) != 0 {
+		t.Errorf("RefreshAheadCandidates() second call = %v, want empty", candidates)
+	}
+
+	// 写入新条目后访问计数重置
+	cm.Set("hot.com", &CacheEntry{IPv4: []string{"1.2.3.4"}, TTL: 100, QueryTime: time.Now()})
+	if _, _, needAsync := cm.Get("hot.com"); needAsync {
+		t.Error("Get() should not need refresh for a freshly written entry")
+	}
+}
+
+// TestCacheManager_RefreshAheadDisabled 测试未启用提前刷新时不触发刷新
+func TestCacheManager_RefreshAheadDisabled(t *testing.T) {
+	config := DefaultConfig()
+	config.AccountID = "test123"
+
+	cm := NewCacheManager(config)
+	cm.Set("hot.com", &CacheEntry{
+		IPv4:      []string{"1.2.3.4"},
+		TTL:       100,
+		QueryTime: time.Now().Add(-90 * time.Second),
+	})
+
+	for i := 0; i < 3; i++ {
+		if _, _, needAsync := cm.Get("hot.com"); needAsync {
+			t.Error("Get() should not need refresh when refresh-ahead is disabled")
+		}
+	}
+	if candidates := cm.RefreshAheadCandidates(); len(candidates) != 0 {
+		t.Errorf("RefreshAheadCandidates() = %v, want empty", candidates)
+	}
+}
+
+// TestCacheManager_BoundedStale 测试有界过期缓存窗口
+func TestCacheManager_BoundedStale(t *testing.T) {
+	config := DefaultConfig()
+	config.AccountID = "test123"
+	config.MaxStaleWhileRevalidate = time.Minute
+
+	cm := NewCacheManager(config)
+
+	// 过期 30 秒
+	cm.Set("recent.com", &CacheEntry{
+		IPv4:      []string{"1.2.3.4"},
+		TTL:       60,
+		QueryTime: time.Now().Add(-90 * time.Second),
+	})
+	// 过期 1 小时
+	cm.Set("old.com", &CacheEntry{
+		IPv4:      []string{"5.6.7.8"},
+		TTL:       60,
+		QueryTime: time.Now().Add(-61 * time.Minute),
+	})
+
+	entry, hit, needAsync := cm.Get("recent.com")
+	if !hit || !needAsync {
+		t.Errorf("Get() within stale window hit=%v needAsync=%v, want stale hit", hit, needAsync)
+	}
+	if entry != nil && !entry.ToResolveResult("recent.com").Stale {
+		t.Error("ToResolveResult() should flag expired entry as stale")
+	}
+
+	if _, hit, _ := cm.Get("old.com"); hit {
+		t.Error("Get() should miss for entry beyond stale window")
+	}
+
+	// 单次调用收紧窗口
+	if _, hit, _ := cm.Lookup("recent.com", 10*time.Second); hit {
+		t.Error("Lookup() should miss when staleness exceeds maxStale")
+	}
+	// 单次调用不限制
+	if _, hit, _ := cm.Lookup("old.com", -1); !hit {
+		t.Error("Lookup() with negative maxStale should hit any expired entry")
+	}
+
+	// stale-if-error 兜底
+	if _, ok := cm.GetStale("old.com", 2*time.Hour); !ok {
+		t.Error("GetStale() should return entry within window")
+	}
+	if _, ok := cm.GetStale("old.com", 30*time.Minute); ok {
+		t.Error("GetStale() should not return entry beyond window")
+	}
+	if _, ok := cm.GetStale("old.com", 0); ok {
+		t.Error("GetStale() with zero window should be disabled")
+	}
+}
+
+func TestCacheManager_NegativeCache(t *testing.T) {
+	config := DefaultConfig()
+	config.AccountID = "test123"
+	config.NegativeCacheTTL = 10 * time.Second
+	config.NegativeCacheMaxTTL = 30 * time.Second
+	cm := NewCacheManager(config)
+
+	if err := cm.GetNegative("example.com"); err != nil {
+		t.Fatalf("GetNegative() = %v, want nil before any failure", err)
+	}
+
+	// 连续失败时指数退避并受上限约束
+	wantTTLs := []time.Duration{10 * time.Second, 20 * time.Second, 30 * time.Second, 30 * time.Second}
+	for i, want := range wantTTLs {
+		if got := cm.SetNegative("example.com", ErrNoAddresses); got != want {
+			t.Errorf("SetNegative() #%d = %v, want %v", i+1, got, want)
+		}
+	}
+
+	if err := cm.GetNegative("example.com"); !errors.Is(err, ErrNoAddresses) {
+		t.Errorf("GetNegative() = %v, want ErrNoAddresses", err)
+	}
+	if got := cm.NegativeCount(); got != 1 {
+		t.Errorf("NegativeCount() = %d, want 1", got)
+	}
+
+	// 写入正缓存后清除负缓存
+	cm.Set("example.com", &CacheEntry{IPv4: []string{"1.2.3.4"}, TTL: 60, QueryTime: time.Now()})
+	if err := cm.GetNegative("example.com"); err != nil {
+		t.Errorf("GetNegative() after Set = %v, want nil", err)
+	}
+	if got := cm.SetNegative("example.com", ErrNoAddresses); got != 10*time.Second {
+		t.Errorf("SetNegative() after Set = %v, want backoff reset to 10s", got)
+	}
+}
+
+func TestCacheManager_NegativeCacheDisabled(t *testing.T) {
+	config := DefaultConfig()
+	config.AccountID = "test123"
+	cm := NewCacheManager(config)
+
+	if got := cm.SetNegative("example.com", ErrNoAddresses); got != 0 {
+		t.Errorf("SetNegative() = %v, want 0 when disabled", got)
+	}
+	if err := cm.GetNegative("example.com"); err != nil {
+		t.Errorf("GetNegative() = %v, want nil when disabled", err)
+	}
+}
+
+func TestCacheManager_AccessStatsPersistence(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "httpdns_cache_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	cm := &CacheManager{
+		cache:      make(map[string]*CacheEntry),
+		enabled:    true,
+		persistent: true,
+		store:      &FileStore{dir: tempDir},
+		threshold:  0,
+	}
+
+	// hot.com 访问最多但已过期，fresh.com 次之但仍有效，warm.com 最少且已过期
+	expired := time.Now().Add(-time.Hour)
+	cm.Set("hot.com", &CacheEntry{IPv4: []string{"1.1.1.1"}, TTL: 60, QueryTime: time.Now()})
+	cm.Set("warm.com", &CacheEntry{IPv4: []string{"2.2.2.2"}, TTL: 60, QueryTime: time.Now()})
+	cm.Set("fresh.com", &CacheEntry{IPv4: []string{"3.3.3.3"}, TTL: 3600, QueryTime: time.Now()})
+	for i := 0; i < 5; i++ {
+		cm.Get("hot.com")
+	}
+	for i := 0; i < 3; i++ {
+		cm.Get("fresh.com")
+	}
+	cm.Get("warm.com")
+	cm.cache["hot.com"].QueryTime = expired
+	cm.cache["warm.com"].QueryTime = expired
+
+	cm.doSaveResolveCache()
+
+	cm2 := &CacheManager{
+		cache:      make(map[string]*CacheEntry),
+		enabled:    true,
+		persistent: true,
+		store:      &FileStore{dir: tempDir},
+		threshold:  0,
+	}
+	if err := cm2.LoadFromDisk(); err != nil {
+		t.Fatalf("LoadFromDisk() error = %v", err)
+	}
+
+	// 过期记录不加载，但访问统计保留
+	if _, ok := cm2.cache["hot.com"]; ok {
+		t.Error("expired record should not be loaded")
+	}
+	if stats := cm2.stats["hot.com"]; stats == nil || stats.total != 6 {
+		t.Errorf("restored stats for hot.com = %+v, want total 6", stats)
+	}
+
+	got := cm2.WarmupCandidates(10)
+	want := []string{"hot.com", "warm.com"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("WarmupCandidates() = %v, want %v", got, want)
+	}
+	if got := cm2.WarmupCandidates(1); len(got) != 1 || got[0] != "hot.com" {
+		t.Errorf("WarmupCandidates(1) = %v, want [hot.com]", got)
+	}
+
+	// 前N个中仍有效的域名不需要预热
+	cm2.Set("hot.com", &CacheEntry{IPv4: []string{"1.1.1.1"}, TTL: 60, QueryTime: time.Now()})
+	if got := cm2.WarmupCandidates(2); len(got) != 0 {
+		t.Errorf("WarmupCandidates(2) = %v, want none", got)
+	}
+}
+
+// TestCacheManager_AccessStatsBounded 测试访问统计超过上限时淘汰最久未访问的域名
+func TestCacheManager_AccessStatsBounded(t *testing.T) {
+	config := DefaultConfig()
+	config.AccountID = "test123"
+	cm := NewCacheManager(config)
+
+	for i := 0; i < maxAccessStats; i++ {
+		cm.Set(fmt.Sprintf("d%d.com", i), &CacheEntry{IPv4: []string{"1.2.3.4"}, TTL: 60, QueryTime: time.Now()})
+	}
+	cm.stats["d0.com"].lastAccess = time.Now().Add(-time.Hour)
+	cm.stats["d1.com"].lastAccess = time.Now().Add(-2 * accessStatsRetention)
+	if len(cm.stats) != maxAccessStats {
+		t.Fatalf("access stats = %d, want %d", len(cm.stats), maxAccessStats)
+	}
+
+	// 先清理保留期外的域名
+	cm.Set("new.com", &CacheEntry{IPv4: []string{"1.2.3.4"}, TTL: 60, QueryTime: time.Now()})
+	if _, ok := cm.stats["d1.com"]; ok || len(cm.stats) != maxAccessStats {
+		t.Errorf("access stats = %d, want %d with d1.com pruned", len(cm.stats), maxAccessStats)
+	}
+
+	// 仍然超出时淘汰最久未访问的域名
+	cm.Set("new2.com", &CacheEntry{IPv4: []string{"1.2.3.4"}, TTL: 60, QueryTime: time.Now()})
+	if got, want := len(cm.stats), maxAccessStats*9/10; got != want {
+		t.Errorf("access stats after prune = %d, want %d", got, want)
+	}
+	if _, ok := cm.stats["d0.com"]; ok {
+		t.Error("least recently accessed stats for d0.com should be pruned")
+	}
+	for _, domain := range []string{"new.com", "new2.com"} {
+		if _, ok := cm.stats[domain]; !ok {
+			t.Errorf("access stats for %s should be kept", domain)
+		}
+	}
+}
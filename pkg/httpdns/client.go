@@ -2,6 +2,7 @@ package httpdns
 
 import (
 	"context"
+	"io"
 	"sync"
 	"time"
 )
@@ -61,6 +62,78 @@ func (c *client) start() {
 	c.wg.Add(1)
 
 	go c.periodicUpdateServiceIPs()
+
+	if c.config.EnableRefreshAhead {
+		c.wg.Add(1)
+		go c.periodicRefreshAhead()
+	}
+
+	// 预热依赖持久化缓存中的访问统计
+	if c.config.EnablePersistentCache && c.config.WarmupTopN > 0 {
+		c.wg.Add(1)
+		go c.warmup()
+	}
+
+	if c.config.EnablePersistentCache && c.config.PersistentSyncInterval > 0 {
+		c.wg.Add(1)
+		go c.periodicSyncFromStore()
+	}
+}
+
+// periodicSyncFromStore 定时合并其他进程写入持久化存储的记录
+func (c *client) periodicSyncFromStore() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(c.config.PersistentSyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := c.cacheManager.SyncFromStore(); err != nil && c.config.Logger != nil {
+				c.config.Logger.Printf("Failed to sync persistent cache: %v", err)
+			}
+		case <-c.stopCh:
+			return
+		}
+	}
+}
+
+// warmup 启动时在后台预热高频域名，客户端关闭时中止
+func (c *client) warmup() {
+	defer c.wg.Done()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-c.stopCh:
+			cancel()
+		}
+	}()
+
+	c.resolver.Warmup(ctx)
+}
+
+// refreshAheadScanInterval 扫描热点缓存的间隔
+const refreshAheadScanInterval = time.Second
+
+// periodicRefreshAhead 定时扫描并提前刷新热点缓存
+func (c *client) periodicRefreshAhead() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(refreshAheadScanInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.resolver.RefreshAhead()
+		case <-c.stopCh:
+			return
+		}
+	}
 }
 
 // periodicUpdateServiceIPs 定时更新服务IP
@@ -115,6 +188,77 @@ func (c *client) ResolveBatch(ctx context.Context, domains []string, opts ...Res
 	return c.resolver.ResolveBatch(ctx, domains, opts...)
 }
 
+// ResolveBatchMap 批量解析域名，返回以规范化域名为键的结果
+func (c *client) ResolveBatchMap(ctx context.Context, domains []string, opts ...ResolveOption) (map[string]*ResolveResult, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	if !c.started {
+		return nil, NewHTTPDNSError("client_stopped", "", ErrServiceUnavailable)
+	}
+
+	return c.resolver.ResolveBatchMap(ctx, domains, opts...)
+}
+
+// ResolveStream 流式解析域名
+func (c *client) ResolveStream(ctx context.Context, domains <-chan string, opts ...ResolveOption) <-chan *ResolveResult {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	if !c.started {
+		// 客户端已关闭：消费输入并为每个域名返回错误结果
+		out := make(chan *ResolveResult)
+		go func() {
+			defer close(out)
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case domain, ok := <-domains:
+					if !ok {
+						return
+					}
+					result := newErrorResult(normalizeDomain(domain), "", NewHTTPDNSError("client_stopped", domain, ErrServiceUnavailable))
+					select {
+					case out <- result:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}()
+		return out
+	}
+
+	return c.resolver.ResolveStream(ctx, domains, opts...)
+}
+
+// Watch 监听域名的IP变化
+func (c *client) Watch(ctx context.Context, domain string, opts ...ResolveOption) <-chan ResolveEvent {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	if !c.started {
+		events := make(chan ResolveEvent)
+		close(events)
+		return events
+	}
+
+	// 客户端关闭时同时结束监听
+	ctx, cancel := context.WithCancel(ctx)
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		defer cancel()
+		select {
+		case <-ctx.Done():
+		case <-c.stopCh:
+		}
+	}()
+
+	return c.resolver.Watch(ctx, domain, opts...)
+}
+
 // ResolveAsync 异步解析域名
 func (c *client) ResolveAsync(ctx context.Context, domain string, callback func(*ResolveResult, error), opts ...ResolveOption) {
 	c.mutex.RLock()
@@ -141,6 +285,9 @@ func (c *client) Close() error {
 	close(c.stopCh)
 	c.wg.Wait()
 
+	// 写入尚未保存的缓存更新
+	c.cacheManager.SavePending()
+
 	return nil
 }
 
@@ -198,6 +345,49 @@ func (c *client) GetServiceIPs() []string {
 	return c.resolver.httpClient.serviceIPManager.GetServiceIPs()
 }
 
+// GetServiceIPStats 获取各服务IP的健康评分和熔断状态
+func (c *client) GetServiceIPStats() []ServiceIPStats {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	if !c.started {
+		return nil
+	}
+
+	return c.resolver.httpClient.ServiceIPStats()
+}
+
+// ExportCache 导出缓存快照
+func (c *client) ExportCache(w io.Writer) error {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	if !c.started {
+		return NewHTTPDNSError("client_stopped", "", ErrServiceUnavailable)
+	}
+
+	if err := c.cacheManager.Export(w); err != nil {
+		return NewHTTPDNSError("export_cache", "", err)
+	}
+	return nil
+}
+
+// ImportCache 导入缓存快照，返回导入的条目数
+func (c *client) ImportCache(r io.Reader, policy ImportPolicy) (int, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	if !c.started {
+		return 0, NewHTTPDNSError("client_stopped", "", ErrServiceUnavailable)
+	}
+
+	imported, err := c.cacheManager.Import(r, policy)
+	if err != nil {
+		return 0, NewHTTPDNSError("import_cache", "", err)
+	}
+	return imported, nil
+}
+
 // IsHealthy 检查客户端健康状态
 func (c *client) IsHealthy() bool {
 	c.mutex.RLock()
@@ -205,3 +395,17 @@ func (c *client) IsHealthy() bool {
 
 	return c.started
 }
+
+// Health 返回客户端健康状态详情
+func (c *client) Health() HealthStatus {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	skew := c.resolver.httpClient.ClockSkew()
+	return HealthStatus{
+		Healthy:           c.started,
+		ServiceIPs:        len(c.resolver.httpClient.serviceIPManager.GetServiceIPs()),
+		ClockSkew:         skew,
+		ClockSkewExceeded: absDuration(skew) >= c.config.SignatureExpireTime,
+	}
+}
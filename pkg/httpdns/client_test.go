@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -243,6 +244,123 @@ func TestClient_Close(t *testing.T) {
 	}
 }
 
+func TestClient_WatchStopsOnClose(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/test123/ss" {
+			serverAddr := server.URL[7:]
+			response := map[string]interface{}{
+				"service_ip": []string{serverAddr},
+			}
+			json.NewEncoder(w).Encode(response)
+		} else if r.URL.Path == "/test123/d" {
+			response := HTTPDNSResponse{
+				Host: "example.com",
+				IPs:  []string{"1.2.3.4"},
+				TTL:  300,
+			}
+			json.NewEncoder(w).Encode(response)
+		}
+	}))
+	defer server.Close()
+
+	config := DefaultConfig()
+	config.AccountID = "test123"
+	config.BootstrapIPs = []string{server.URL[7:]}
+
+	client, err := NewClient(config)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	events := client.Watch(context.Background(), "example.com")
+	select {
+	case <-events:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Watch() initial event timeout")
+	}
+
+	client.Close()
+
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("Watch() channel should be closed after client Close()")
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("Watch() channel not closed after client Close()")
+	}
+}
+
+// TestClient_RefreshAhead 测试热点域名在过期前被后台刷新
+func TestClient_RefreshAhead(t *testing.T) {
+	var singleRequests, batchRequests int32
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/test123/ss" {
+			serverAddr := server.URL[7:]
+			response := map[string]interface{}{
+				"service_ip": []string{serverAddr},
+			}
+			json.NewEncoder(w).Encode(response)
+		} else if r.URL.Path == "/test123/d" {
+			atomic.AddInt32(&singleRequests, 1)
+			response := HTTPDNSResponse{
+				Host: "example.com",
+				IPs:  []string{"1.2.3.4"},
+				TTL:  2,
+			}
+			json.NewEncoder(w).Encode(response)
+		} else if r.URL.Path == "/test123/resolve" {
+			atomic.AddInt32(&batchRequests, 1)
+			response := BatchResolveResponse{
+				DNS: []HTTPDNSResponse{
+					{Host: "example.com", IPs: []string{"1.2.3.4"}, TTL: 2},
+				},
+			}
+			json.NewEncoder(w).Encode(response)
+		}
+	}))
+	defer server.Close()
+
+	config := DefaultConfig()
+	config.AccountID = "test123"
+	config.BootstrapIPs = []string{server.URL[7:]}
+	config.EnableRefreshAhead = true
+	config.RefreshAheadFraction = 0.5
+
+	client, err := NewClient(config)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	if _, err := client.Resolve(ctx, "example.com"); err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+
+	refreshed := func() bool {
+		return atomic.LoadInt32(&singleRequests) > 1 || atomic.LoadInt32(&batchRequests) > 0
+	}
+
+	// 持续访问使其成为热点，在过期前应被后台刷新，且访问始终命中缓存
+	deadline := time.Now().Add(1900 * time.Millisecond)
+	for time.Now().Before(deadline) && !refreshed() {
+		result, err := client.Resolve(ctx, "example.com")
+		if err != nil || len(result.IPv4) == 0 {
+			t.Fatalf("Resolve() during refresh-ahead = %v, %v", result, err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	if !refreshed() {
+		t.Error("hot domain should be refreshed in the background before expiry")
+	}
+}
+
 func TestClient_ClosedOperations(t *testing.T) {
 	config := DefaultConfig()
 	config.AccountID = "test123"
@@ -268,6 +386,20 @@ func TestClient_ClosedOperations(t *testing.T) {
 		t.Error("ResolveBatch() should return error after client is closed")
 	}
 
+	_, err = client.ResolveBatchMap(ctx, []string{"example.com"})
+	if err == nil {
+		t.Error("ResolveBatchMap() should return error after client is closed")
+	}
+
+	domains := make(chan string, 1)
+	domains <- "example.com"
+	close(domains)
+	for result := range client.ResolveStream(ctx, domains) {
+		if result.Error == nil {
+			t.Error("ResolveStream() should return error results after client is closed")
+		}
+	}
+
 	errorChan := make(chan error, 1)
 	client.ResolveAsync(ctx, "example.com", func(result *ResolveResult, err error) {
 		errorChan <- err
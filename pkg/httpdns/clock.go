@@ -0,0 +1,73 @@
+package httpdns
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// 时钟偏差估算参数
+const (
+	clockSkewSmoothing    = 4                      // 平滑系数，新样本权重为 1/clockSkewSmoothing
+	clockSkewResetJump    = 5 * time.Second        // 新样本与当前估计相差超过该值时直接采用（如本地时钟被校正）
+	clockSkewDateRounding = 500 * time.Millisecond // Date 头精度为秒，取区间中点
+)
+
+// clockSkew 根据服务端 Date 响应头估算的时钟偏差（服务端时间 - 本地时间）
+type clockSkew struct {
+	mutex     sync.RWMutex
+	offset    time.Duration
+	samples   int64
+	updatedAt time.Time
+}
+
+// observe 根据响应的 Date 头更新偏差估计，返回更新后的偏差和是否有效
+func (s *clockSkew) observe(resp *http.Response, sentAt, receivedAt time.Time) (time.Duration, bool) {
+	if s == nil || resp == nil {
+		return 0, false
+	}
+	serverTime, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return 0, false
+	}
+
+	// 以请求往返的中点作为服务端生成 Date 的本地时刻
+	localTime := sentAt.Add(receivedAt.Sub(sentAt) / 2)
+	sample := serverTime.Add(clockSkewDateRounding).Sub(localTime)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	diff := sample - s.offset
+	if s.samples == 0 || diff > clockSkewResetJump || diff < -clockSkewResetJump {
+		s.offset = sample
+	} else {
+		s.offset += diff / clockSkewSmoothing
+	}
+	s.samples++
+	s.updatedAt = receivedAt
+	return s.offset, true
+}
+
+// Offset 返回当前偏差估计，尚无样本时为 0
+func (s *clockSkew) Offset() time.Duration {
+	if s == nil {
+		return 0
+	}
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.offset
+}
+
+// Now 返回按偏差校正后的当前时间
+func (s *clockSkew) Now() time.Time {
+	return time.Now().Add(s.Offset())
+}
+
+// absDuration 返回时长的绝对值
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -0,0 +1,168 @@
+package httpdns
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClockSkew_Observe(t *testing.T) {
+	response := func(date time.Time) *http.Response {
+		header := http.Header{}
+		header.Set("Date", date.UTC().Format(http.TimeFormat))
+		return &http.Response{Header: header}
+	}
+
+	s := &clockSkew{}
+	now := time.Now().Truncate(time.Second)
+
+	if _, ok := s.observe(&http.Response{Header: http.Header{}}, now, now); ok {
+		t.Error("observe() without Date header should be ignored")
+	}
+	if s.Offset() != 0 {
+		t.Errorf("Offset() = %v, want 0 before any sample", s.Offset())
+	}
+
+	// 首个样本直接采用
+	s.observe(response(now.Add(time.Minute)), now, now)
+	if got := s.Offset(); got != time.Minute+clockSkewDateRounding {
+		t.Errorf("Offset() = %v, want %v", got, time.Minute+clockSkewDateRounding)
+	}
+
+	// 小幅变化平滑处理
+	s.observe(response(now.Add(time.Minute+4*time.Second)), now, now)
+	if got, want := s.Offset(), time.Minute+clockSkewDateRounding+time.Second; got != want {
+		t.Errorf("Offset() after smoothing = %v, want %v", got, want)
+	}
+
+	// 大幅跳变直接采用新样本
+	s.observe(response(now.Add(-time.Hour)), now, now)
+	if got, want := s.Offset(), -time.Hour+clockSkewDateRounding; got != want {
+		t.Errorf("Offset() after jump = %v, want %v", got, want)
+	}
+}
+
+// newSkewedServer 创建时钟快 skew 的测试服务器，签名时间戳超出服务端有效期时返回 SignatureExpired
+func newSkewedServer(t *testing.T, skew time.Duration, requests *int32) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serverNow := time.Now().Add(skew)
+		w.Header().Set("Date", serverNow.UTC().Format(http.TimeFormat))
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/ss") {
+			json.NewEncoder(w).Encode(map[string]interface{}{"service_ip": []string{server.URL[7:]}})
+			return
+		}
+
+		atomic.AddInt32(requests, 1)
+		query := r.URL.Query()
+		expireAt, _ := strconv.ParseInt(query.Get("t"), 10, 64)
+		if expireAt < serverNow.Unix() || expireAt > serverNow.Add(time.Minute).Unix() {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]string{"code": "SignatureExpired"})
+			return
+		}
+		json.NewEncoder(w).Encode(HTTPDNSResponse{Host: query.Get("host"), IPs: []string{"1.2.3.4"}, TTL: 60})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClient_ClockSkewCompensation(t *testing.T) {
+	var requests int32
+	server := newSkewedServer(t, 10*time.Minute, &requests)
+
+	config := DefaultConfig()
+	config.AccountID = "test123"
+	config.SecretKey = "secret"
+	config.BootstrapIPs = []string{server.URL[7:]}
+	config.EnableMemoryCache = false
+	config.EnableMetrics = true
+
+	client, err := NewClient(config)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	if _, err := client.Resolve(ctx, "example.com"); err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	// 首次请求被拒绝后根据响应的 Date 头校正并重新签名
+	if got := atomic.LoadInt32(&requests); got != 2 {
+		t.Errorf("requests = %d, want 2 (rejected + re-signed)", got)
+	}
+
+	if _, err := client.Resolve(ctx, "example.com"); err != nil {
+		t.Fatalf("second Resolve() error = %v", err)
+	}
+	if got := atomic.LoadInt32(&requests); got != 3 {
+		t.Errorf("requests = %d, want 3 (compensated signature accepted)", got)
+	}
+
+	health := client.Health()
+	if !health.Healthy || !health.ClockSkewExceeded {
+		t.Errorf("Health() = %+v, want healthy with clock skew exceeded", health)
+	}
+	if health.ClockSkew < 9*time.Minute || health.ClockSkew > 11*time.Minute {
+		t.Errorf("Health().ClockSkew = %v, want about 10m", health.ClockSkew)
+	}
+	if stats := client.GetMetrics(); stats.ClockSkew != health.ClockSkew {
+		t.Errorf("GetMetrics().ClockSkew = %v, want %v", stats.ClockSkew, health.ClockSkew)
+	}
+}
+
+func TestClient_ClockSkewRejected(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/ss") {
+			json.NewEncoder(w).Encode(map[string]interface{}{"service_ip": []string{r.Host}})
+			return
+		}
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"code": "SignatureExpired"})
+	}))
+	defer server.Close()
+
+	config := DefaultConfig()
+	config.AccountID = "test123"
+	config.SecretKey = "secret"
+	config.BootstrapIPs = []string{server.URL[7:]}
+	config.EnableMemoryCache = false
+
+	client, err := NewClient(config)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	defer client.Close()
+
+	_, err = client.Resolve(context.Background(), "example.com")
+	if !errors.Is(err, ErrClockSkew) {
+		t.Fatalf("Resolve() error = %v, want ErrClockSkew", err)
+	}
+	if got := atomic.LoadInt32(&requests); got != 2 {
+		t.Errorf("requests = %d, want 2 (one immediate re-signed retry)", got)
+	}
+	if health := client.Health(); health.ClockSkewExceeded {
+		t.Errorf("Health() = %+v, want no clock skew without Date offset", health)
+	}
+
+	// 普通的 403 不视为时钟偏差
+	var plain HTTPDNSClient
+	plain.config = config
+	plain.clock = &clockSkew{}
+	serverErr := newServerError(&http.Response{StatusCode: http.StatusForbidden, Body: http.NoBody})
+	if plain.isClockSkewRejection(serverErr, 0) {
+		t.Error("isClockSkewRejection() = true for plain 403, want false")
+	}
+}
@@ -1,6 +1,9 @@
 package httpdns
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 // 默认EMAS HTTPDNS启动IP（中国内地）
 var DefaultBootstrapIPs = []string{
@@ -18,16 +21,37 @@ var DefaultBootstrapDomain = "resolvers-cn.httpdns.aliyuncs.com"
 // 默认HTTPS SNI域名
 var DefaultHTTPSSNI = "203.107.1.1"
 
+// DefaultMaxBatchDomains 服务端单次批量解析支持的最大域名数
+const DefaultMaxBatchDomains = 5
+
+// DefaultBatchConcurrency 批量解析分片的默认并发数
+const DefaultBatchConcurrency = 4
+
 // Config 客户端配置
 type Config struct {
 	// 认证信息
 	AccountID string
 	SecretKey string // 可选，用于鉴权解析
 
+	// 鉴权扩展配置
+	CredentialProvider CredentialProvider // 可选，运行时提供和轮换 AccountID/SecretKey，设置后优先于 SecretKey
+	Signer             Signer             // 可选，请求签名器，默认 MD5Signer
+
 	// 网络配置
-	BootstrapIPs []string // 默认使用DefaultBootstrapIPs，支持用户自定义
-	Timeout      time.Duration
-	MaxRetries   int // 重试次数，默认0不重试，避免频率限制
+	BootstrapIPs   []string      // 默认使用DefaultBootstrapIPs，支持用户自定义
+	Timeout        time.Duration // 解析的默认整体超时（可通过 WithTimeout 覆盖），默认5秒
+	AttemptTimeout time.Duration // 单次请求尝试的超时上限，默认0不限；整体剩余时间按剩余尝试次数均分，避免单个慢服务IP耗尽时间
+	MaxRetries     int           // 重试次数，默认0不重试，避免频率限制；未设置 RetryPolicy 时用于默认策略
+
+	// 对冲请求配置（首个服务IP在对冲延迟内未响应时向另一个服务IP发送相同请求，使用最先成功的响应）
+	EnableHedging bool          // 是否启用对冲请求，默认false
+	HedgeDelay    time.Duration // 固定对冲延迟，默认0表示使用最近成功请求延迟的 p95
+	HedgeBudget   float64       // 对冲请求数占请求数的比例上限，默认0.1
+
+	// 重试策略配置（默认 NewExponentialBackoff(MaxRetries)）
+	RetryPolicy          RetryPolicy // 前台解析的重试策略
+	AsyncRetryPolicy     RetryPolicy // 后台刷新（过期缓存刷新、提前刷新、预热）的重试策略，默认同 RetryPolicy
+	ServiceIPRetryPolicy RetryPolicy // 获取服务IP的重试策略（每次尝试遍历全部启动IP），默认不重试
 
 	// 功能开关
 	EnableHTTPS   bool // 是否使用HTTPS，默认false使用HTTP
@@ -45,38 +69,132 @@ type Config struct {
 	EnablePersistentCache bool          // 是否启用持久化缓存，默认false
 	CacheExpireThreshold  time.Duration // 持久化缓存过期阈值，默认0
 
+	// 持久化存储配置
+	CacheDir        string          // 持久化缓存根目录，实际目录为 CacheDir/<AccountID>，默认使用 os.UserCacheDir()/alicloud_httpdns
+	PersistentStore PersistentStore // 自定义持久化存储，设置后忽略 CacheDir
+
+	// 多进程共享配置（同一账号的多个进程共用持久化目录时使用）
+	PersistentSyncInterval time.Duration // 定时从持久化存储合并其他进程写入的记录的间隔，默认30秒，0表示不同步
+
+	// 增量持久化配置（存储实现 JournalStore 时以追加日志的方式写入更新，默认 FileStore 支持）
+	PersistentFlushInterval    time.Duration // 写入防抖间隔，间隔内的多次更新合并为一次写入，默认1秒，0表示立即写入
+	PersistentCompactThreshold int           // 日志记录数达到该值时压缩为完整快照，默认1000
+
+	// 持久化加密配置（AES-GCM，仅对默认文件存储生效）
+	CacheKeyProvider          KeyProvider // 加密密钥提供者，设置后加密写入，拒绝加载未加密或被篡改的文件
+	EncryptCacheWithSecretKey bool        // 未设置 CacheKeyProvider 时使用 SecretKey 派生的密钥加密，默认false
+
+	// 有界过期缓存配置（AllowExpiredCache 为 true 时 stale-while-revalidate 不限时长）
+	MaxStaleWhileRevalidate time.Duration // 过期不超过该时长的缓存直接返回并后台刷新，默认0
+	MaxStaleIfError         time.Duration // 网络请求失败时，过期不超过该时长的缓存可作为兜底返回，默认0
+
+	// 负缓存配置（空结果或解析失败的域名在退避期内直接返回错误，不再请求网络）
+	NegativeCacheTTL    time.Duration // 负缓存初始时长，连续失败时指数退避，默认0不启用
+	NegativeCacheMaxTTL time.Duration // 负缓存退避上限，默认5分钟
+
+	// 异常结果保护配置（空结果或部分结果不覆盖已有的非空缓存，两项均为0时不启用）
+	LastKnownGoodAttempts    int           // 连续异常结果最多忽略的次数，默认0不限次数
+	LastKnownGoodDuration    time.Duration // 自首次异常起最长保护时长，默认0不限时长
+	LastKnownGoodShrinkRatio float64       // 某地址族的地址数少于缓存的该比例时视为部分结果，默认0.5，0表示仅检查地址族缺失
+
+	// TTL策略配置
+	TTLPolicy         TTLPolicy            // 全局TTL策略，默认使用服务端 ttl 且不做约束
+	DomainTTLPolicies map[string]TTLPolicy // 按域名的TTL策略，键为域名或 *.后缀，匹配时完全替代全局策略
+
+	// 提前刷新配置（热点缓存在过期前后台刷新，避免同步未命中）
+	EnableRefreshAhead   bool          // 是否启用提前刷新，默认false
+	RefreshAheadFraction float64       // 缓存存活超过TTL的该比例后触发刷新，默认0.75
+	RefreshAheadWindow   time.Duration // 热点判定窗口，窗口内被访问过的条目才会提前刷新，默认1分钟
+	RefreshAheadMinHits  int64         // 自上次写入以来的最少访问次数，默认1
+
+	// 启动预热配置（依赖持久化缓存中的访问统计）
+	WarmupTopN int // 启动时后台批量刷新访问最多的前N个域名，默认0不预热
+
+	// 批量解析配置
+	MaxBatchDomains  int // 单次批量请求最多携带的域名数，默认5（服务端限制）
+	BatchConcurrency int // 批量解析分片的最大并发请求数，默认4
+
 	// 日志配置
 	Logger Logger
+
+	// 钩子配置
+	OnRequestAttempt func(RequestAttempt) // 可选，每次请求尝试结束后调用（包括成功的尝试），需并发安全
 }
 
 // DefaultConfig 返回默认配置
 func DefaultConfig() *Config {
 	return &Config{
-		BootstrapIPs:          DefaultBootstrapIPs,
-		Timeout:               5 * time.Second,
-		MaxRetries:            0,     // 默认不重试，避免频率限制问题
-		EnableHTTPS:           false, // 默认使用HTTP
-		EnableMetrics:         false,
-		HTTPSSNIHost:          DefaultHTTPSSNI,  // 默认HTTPS SNI主机名
-		SignatureExpireTime:   30 * time.Second, // 默认30秒签名过期时间
-		EnableMemoryCache:     true,             // 默认启用内存缓存
-		AllowExpiredCache:     false,            // 默认不允许使用过期缓存
-		EnablePersistentCache: false,            // 默认不启用持久化缓存
-		CacheExpireThreshold:  0,                // 默认持久化缓存严格按TTL过期
+		BootstrapIPs:               DefaultBootstrapIPs,
+		Timeout:                    5 * time.Second,
+		MaxRetries:                 0,                  // 默认不重试，避免频率限制问题
+		HedgeBudget:                DefaultHedgeBudget, // 默认对冲请求不超过10%
+		EnableHTTPS:                false,              // 默认使用HTTP
+		EnableMetrics:              false,
+		HTTPSSNIHost:               DefaultHTTPSSNI,         // 默认HTTPS SNI主机名
+		SignatureExpireTime:        30 * time.Second,        // 默认30秒签名过期时间
+		EnableMemoryCache:          true,                    // 默认启用内存缓存
+		AllowExpiredCache:          false,                   // 默认不允许使用过期缓存
+		EnablePersistentCache:      false,                   // 默认不启用持久化缓存
+		CacheExpireThreshold:       0,                       // 默认持久化缓存严格按TTL过期
+		NegativeCacheTTL:           0,                       // 默认不启用负缓存
+		NegativeCacheMaxTTL:        5 * time.Minute,         // 默认负缓存最长退避5分钟
+		LastKnownGoodAttempts:      0,                       // 默认不启用异常结果保护
+		LastKnownGoodDuration:      0,                       // 默认不启用异常结果保护
+		LastKnownGoodShrinkRatio:   0.5,                     // 默认地址数减少一半以上视为部分结果
+		EnableRefreshAhead:         false,                   // 默认不启用提前刷新
+		RefreshAheadFraction:       0.75,                    // 默认存活75%TTL后刷新
+		RefreshAheadWindow:         time.Minute,             // 默认1分钟内访问过视为热点
+		RefreshAheadMinHits:        1,                       // 默认访问1次即视为热点
+		WarmupTopN:                 0,                       // 默认不预热
+		PersistentSyncInterval:     30 * time.Second,        // 默认每30秒合并其他进程的更新
+		PersistentFlushInterval:    time.Second,             // 默认1秒内的更新合并写入
+		PersistentCompactThreshold: 1000,                    // 默认日志达到1000条时压缩
+		MaxBatchDomains:            DefaultMaxBatchDomains,  // 默认单次批量请求5个域名
+		BatchConcurrency:           DefaultBatchConcurrency, // 默认批量分片并发4
 	}
 }
 
 // Validate 验证配置
 func (c *Config) Validate() error {
+	// 未配置 AccountID 时使用凭据提供者的初始账号（用于持久化缓存目录等）
+	if c.AccountID == "" && c.CredentialProvider != nil {
+		if creds, err := c.CredentialProvider.Credentials(); err == nil {
+			c.AccountID = creds.AccountID
+		}
+	}
 	if c.AccountID == "" {
 		return ErrInvalidConfig
 	}
+	if c.EncryptCacheWithSecretKey && c.CacheKeyProvider == nil && c.SecretKey == "" {
+		return fmt.Errorf("%w: EncryptCacheWithSecretKey requires SecretKey", ErrInvalidConfig)
+	}
 	if c.Timeout <= 0 {
 		c.Timeout = 5 * time.Second
 	}
+	if c.AttemptTimeout < 0 {
+		c.AttemptTimeout = 0
+	}
+	if c.HedgeDelay < 0 {
+		c.HedgeDelay = 0
+	}
+	if c.HedgeBudget <= 0 {
+		c.HedgeBudget = DefaultHedgeBudget
+	}
+	if c.HedgeBudget > 1 {
+		c.HedgeBudget = 1
+	}
 	if c.MaxRetries < 0 {
 		c.MaxRetries = 0 // 允许0次重试
 	}
+	if c.RetryPolicy == nil {
+		c.RetryPolicy = NewExponentialBackoff(c.MaxRetries)
+	}
+	if c.AsyncRetryPolicy == nil {
+		c.AsyncRetryPolicy = c.RetryPolicy
+	}
+	if c.ServiceIPRetryPolicy == nil {
+		c.ServiceIPRetryPolicy = NewExponentialBackoff(0)
+	}
 	if len(c.BootstrapIPs) == 0 {
 		c.BootstrapIPs = DefaultBootstrapIPs
 	}
@@ -93,5 +211,82 @@ func (c *Config) Validate() error {
 	if c.CacheExpireThreshold < 0 {
 		c.CacheExpireThreshold = 0
 	}
+	if c.MaxStaleWhileRevalidate < 0 {
+		c.MaxStaleWhileRevalidate = 0
+	}
+	if c.MaxStaleIfError < 0 {
+		c.MaxStaleIfError = 0
+	}
+	if c.NegativeCacheTTL < 0 {
+		c.NegativeCacheTTL = 0
+	}
+	if c.NegativeCacheMaxTTL <= 0 {
+		c.NegativeCacheMaxTTL = 5 * time.Minute
+	}
+	if c.NegativeCacheMaxTTL < c.NegativeCacheTTL {
+		c.NegativeCacheMaxTTL = c.NegativeCacheTTL
+	}
+	if c.LastKnownGoodAttempts < 0 {
+		c.LastKnownGoodAttempts = 0
+	}
+	if c.LastKnownGoodDuration < 0 {
+		c.LastKnownGoodDuration = 0
+	}
+	if c.LastKnownGoodShrinkRatio < 0 {
+		c.LastKnownGoodShrinkRatio = 0
+	} else if c.LastKnownGoodShrinkRatio > 1 {
+		c.LastKnownGoodShrinkRatio = 1
+	}
+	c.TTLPolicy.normalize()
+	if len(c.DomainTTLPolicies) > 0 {
+		policies := make(map[string]TTLPolicy, len(c.DomainTTLPolicies))
+		for domain, policy := range c.DomainTTLPolicies {
+			policy.normalize()
+			policies[normalizeDomain(domain)] = policy
+		}
+		c.DomainTTLPolicies = policies
+	}
+	if c.WarmupTopN < 0 {
+		c.WarmupTopN = 0
+	}
+	if c.PersistentSyncInterval < 0 {
+		c.PersistentSyncInterval = 0
+	}
+	if c.PersistentFlushInterval < 0 {
+		c.PersistentFlushInterval = 0
+	}
+	if c.PersistentCompactThreshold <= 0 {
+		c.PersistentCompactThreshold = 1000
+	}
+	// 提前刷新依赖内存缓存
+	if c.EnableRefreshAhead && !c.EnableMemoryCache {
+		c.EnableRefreshAhead = false
+	}
+	if c.RefreshAheadFraction <= 0 || c.RefreshAheadFraction >= 1 {
+		c.RefreshAheadFraction = 0.75
+	}
+	if c.RefreshAheadWindow <= 0 {
+		c.RefreshAheadWindow = time.Minute
+	}
+	if c.RefreshAheadMinHits <= 0 {
+		c.RefreshAheadMinHits = 1
+	}
+	if c.MaxBatchDomains <= 0 {
+		c.MaxBatchDomains = DefaultMaxBatchDomains
+	}
+	if c.BatchConcurrency <= 0 {
+		c.BatchConcurrency = DefaultBatchConcurrency
+	}
+	return nil
+}
+
+// cacheKeyProvider 返回持久化文件加密密钥提供者，未启用加密时返回 nil
+func (c *Config) cacheKeyProvider() KeyProvider {
+	if c.CacheKeyProvider != nil {
+		return c.CacheKeyProvider
+	}
+	if c.EncryptCacheWithSecretKey && c.SecretKey != "" {
+		return NewSecretKeyProvider(c.AccountID, c.SecretKey)
+	}
 	return nil
 }
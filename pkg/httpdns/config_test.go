@@ -94,6 +94,12 @@ func TestConfigValidate(t *testing.T) {
 				if len(tt.config.BootstrapIPs) == 0 {
 					t.Error("BootstrapIPs should be set to default values")
 				}
+				if tt.config.MaxBatchDomains != DefaultMaxBatchDomains {
+					t.Errorf("MaxBatchDomains = %d, want %d", tt.config.MaxBatchDomains, DefaultMaxBatchDomains)
+				}
+				if tt.config.BatchConcurrency != DefaultBatchConcurrency {
+					t.Errorf("BatchConcurrency = %d, want %d", tt.config.BatchConcurrency, DefaultBatchConcurrency)
+				}
 			}
 		})
 	}
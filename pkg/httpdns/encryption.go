@@ -0,0 +1,105 @@
+package httpdns
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"io"
+)
+
+// cacheEncryptionAlgorithm 按密钥长度返回持久化文件加密算法标识，写入文件头
+func cacheEncryptionAlgorithm(key []byte) string {
+	return fmt.Sprintf("AES-%d-GCM", len(key)*8)
+}
+
+// isCacheEncryptionAlgorithm 是否为支持的加密算法标识
+func isCacheEncryptionAlgorithm(name string) bool {
+	switch name {
+	case "AES-128-GCM", "AES-192-GCM", "AES-256-GCM":
+		return true
+	default:
+		return false
+	}
+}
+
+// KeyProvider 持久化文件加密密钥提供者
+// Key 返回 16、24 或 32 字节的 AES 密钥，每次读写文件时调用，实现需保证并发安全
+type KeyProvider interface {
+	Key() ([]byte, error)
+}
+
+// StaticKeyProvider 固定密钥
+type StaticKeyProvider []byte
+
+// Key 返回固定密钥
+func (p StaticKeyProvider) Key() ([]byte, error) {
+	switch len(p) {
+	case 16, 24, 32:
+		return p, nil
+	default:
+		return nil, fmt.Errorf("invalid AES key length %d", len(p))
+	}
+}
+
+// secretKeyProvider 从 SecretKey 派生的密钥
+type secretKeyProvider struct {
+	key []byte
+}
+
+// NewSecretKeyProvider 创建从 SecretKey 派生密钥的提供者，同一账号和 SecretKey 派生出相同的密钥
+func NewSecretKeyProvider(accountID, secretKey string) KeyProvider {
+	mac := hmac.New(sha256.New, []byte(secretKey))
+	mac.Write([]byte("alicloud-httpdns-cache-encryption:" + accountID))
+	return &secretKeyProvider{key: mac.Sum(nil)}
+}
+
+// Key 返回派生的 32 字节密钥
+func (p *secretKeyProvider) Key() ([]byte, error) {
+	return p.key, nil
+}
+
+// newCacheAEAD 根据密钥提供者创建 AES-GCM
+func newCacheAEAD(keys KeyProvider) (cipher.AEAD, error) {
+	key, err := keys.Key()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCacheKeyUnavailable, err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCacheKeyUnavailable, err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealCacheData 加密数据，返回 nonce 与密文拼接的结果，aad 用于绑定文件名和账号
+func sealCacheData(keys KeyProvider, plaintext, aad []byte) ([]byte, error) {
+	aead, err := newCacheAEAD(keys)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+// openCacheData 解密数据，密钥错误或数据被篡改时返回 ErrCorruptCache
+func openCacheData(keys KeyProvider, sealed, aad []byte) ([]byte, error) {
+	aead, err := newCacheAEAD(keys)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("%w: encrypted data too short", ErrCorruptCache)
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, aad)
+	if err != nil {
+		return nil, fmt.Errorf("%w: decryption failed", ErrCorruptCache)
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,234 @@
+package httpdns
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestKeyProviders(t *testing.T) {
+	if _, err := StaticKeyProvider(make([]byte, 32)).Key(); err != nil {
+		t.Errorf("StaticKeyProvider(32 bytes).Key() error = %v", err)
+	}
+	if _, err := StaticKeyProvider(make([]byte, 10)).Key(); err == nil {
+		t.Error("StaticKeyProvider(10 bytes).Key() should fail")
+	}
+
+	key1, _ := NewSecretKeyProvider("test123", "secret").Key()
+	key2, _ := NewSecretKeyProvider("test123", "secret").Key()
+	key3, _ := NewSecretKeyProvider("test456", "secret").Key()
+	if len(key1) != 32 || !bytes.Equal(key1, key2) {
+		t.Errorf("derived key = %x, %x, want stable 32-byte key", key1, key2)
+	}
+	if bytes.Equal(key1, key3) {
+		t.Error("derived keys should differ between accounts")
+	}
+}
+
+func newEncryptedTestStore(t *testing.T, dir string, key byte) *FileStore {
+	t.Helper()
+	store, err := NewEncryptedFileStore(dir, "test123", StaticKeyProvider(bytes.Repeat([]byte{key}, 32)), nil)
+	if err != nil {
+		t.Fatalf("NewEncryptedFileStore() error = %v", err)
+	}
+	return store
+}
+
+func TestFileStore_Encryption(t *testing.T) {
+	dir := t.TempDir()
+	store := newEncryptedTestStore(t, dir, 1)
+	now := time.Now()
+
+	if err := store.SaveResolveCache(&ResolveCacheData{Records: map[string]*CacheEntry{
+		"secret.example.com": {IPv4: []string{"10.1.2.3"}, TTL: 60, QueryTime: now},
+	}}); err != nil {
+		t.Fatalf("SaveResolveCache() error = %v", err)
+	}
+	if err := store.AppendResolveCache([]JournalRecord{
+		{Domain: "journal.example.com", Entry: &CacheEntry{IPv4: []string{"10.4.5.6"}, TTL: 60, QueryTime: now}, Time: now},
+	}); err != nil {
+		t.Fatalf("AppendResolveCache() error = %v", err)
+	}
+	if err := store.SaveServiceIPs(&ServiceIPCacheData{IPs: []string{"203.107.1.1"}, UpdatedAt: now}); err != nil {
+		t.Fatalf("SaveServiceIPs() error = %v", err)
+	}
+
+	// 文件中不包含明文域名和IP
+	for _, name := range []string{resolveCacheFile, resolveJournalFile, serviceIPsFile} {
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("ReadFile(%s) error = %v", name, err)
+		}
+		for _, plain := range []string{"example.com", "10.1.2.3", "10.4.5.6", "203.107.1.1"} {
+			if bytes.Contains(content, []byte(plain)) {
+				t.Errorf("%s contains plaintext %q", name, plain)
+			}
+		}
+	}
+
+	data, err := store.LoadResolveCache()
+	if err != nil || data.Records["secret.example.com"] == nil || data.Records["journal.example.com"] == nil {
+		t.Fatalf("LoadResolveCache() = %+v, %v, want both records", data, err)
+	}
+	if ips, err := store.LoadServiceIPs(); err != nil || ips.IPs[0] != "203.107.1.1" {
+		t.Errorf("LoadServiceIPs() = %+v, %v", ips, err)
+	}
+
+	// 未配置密钥时不加载也不隔离加密文件
+	plain, _ := NewAccountFileStore(dir, "test123", nil)
+	if _, err := plain.LoadServiceIPs(); !errors.Is(err, ErrCacheKeyUnavailable) {
+		t.Errorf("LoadServiceIPs() without key error = %v, want ErrCacheKeyUnavailable", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, serviceIPsFile)); err != nil {
+		t.Errorf("encrypted file should not be quarantined without key: %v", err)
+	}
+}
+
+func TestFileStore_EncryptionKeySizes(t *testing.T) {
+	for _, size := range []int{16, 24, 32} {
+		dir := t.TempDir()
+		store, err := NewEncryptedFileStore(dir, "test123", StaticKeyProvider(bytes.Repeat([]byte{1}, size)), nil)
+		if err != nil {
+			t.Fatalf("NewEncryptedFileStore(%d bytes) error = %v", size, err)
+		}
+		if err := store.SaveResolveCache(&ResolveCacheData{Records: map[string]*CacheEntry{
+			"example.com": {IPv4: []string{"1.2.3.4"}, TTL: 60, QueryTime: time.Now()},
+		}}); err != nil {
+			t.Fatalf("SaveResolveCache(%d bytes) error = %v", size, err)
+		}
+
+		// 文件头按实际密钥长度记录算法
+		var file persistentFile
+		content, _ := os.ReadFile(filepath.Join(dir, resolveCacheFile))
+		if err := json.Unmarshal(content, &file); err != nil {
+			t.Fatalf("Unmarshal() error = %v", err)
+		}
+		if want := fmt.Sprintf("AES-%d-GCM", size*8); file.Header.Encryption != want {
+			t.Errorf("header encryption = %q, want %q", file.Header.Encryption, want)
+		}
+
+		data, err := store.LoadResolveCache()
+		if err != nil || data.Records["example.com"] == nil {
+			t.Errorf("LoadResolveCache(%d bytes) = %+v, %v", size, data, err)
+		}
+	}
+}
+
+func TestFileStore_EncryptionFailClosed(t *testing.T) {
+	saveRecord := func(t *testing.T, store *FileStore) {
+		t.Helper()
+		if err := store.SaveResolveCache(&ResolveCacheData{Records: map[string]*CacheEntry{
+			"example.com": {IPv4: []string{"1.2.3.4"}, TTL: 60, QueryTime: time.Now()},
+		}}); err != nil {
+			t.Fatalf("SaveResolveCache() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		prepare func(t *testing.T, dir string)
+	}{
+		{
+			name: "tampered ciphertext",
+			prepare: func(t *testing.T, dir string) {
+				saveRecord(t, newEncryptedTestStore(t, dir, 1))
+
+				// 修改密文并重新计算校验和，只有认证标签能发现篡改
+				path := filepath.Join(dir, resolveCacheFile)
+				content, _ := os.ReadFile(path)
+				var file persistentFile
+				var sealed []byte
+				if err := json.Unmarshal(content, &file); err != nil {
+					t.Fatalf("Unmarshal() error = %v", err)
+				}
+				json.Unmarshal(file.Data, &sealed)
+				sealed[len(sealed)-1] ^= 0xff
+				file.Data, _ = json.Marshal(sealed)
+				file.Header.Checksum = checksumOf(file.Data)
+				content, _ = json.Marshal(&file)
+				os.WriteFile(path, content, 0600)
+			},
+		},
+		{
+			name: "wrong key",
+			prepare: func(t *testing.T, dir string) {
+				saveRecord(t, newEncryptedTestStore(t, dir, 2))
+			},
+		},
+		{
+			name: "plaintext file",
+			prepare: func(t *testing.T, dir string) {
+				plain, _ := NewAccountFileStore(dir, "test123", nil)
+				saveRecord(t, plain)
+			},
+		},
+		{
+			name: "tampered journal",
+			prepare: func(t *testing.T, dir string) {
+				store := newEncryptedTestStore(t, dir, 1)
+				store.AppendResolveCache([]JournalRecord{
+					{Domain: "example.com", Entry: &CacheEntry{IPv4: []string{"1.2.3.4"}, TTL: 60, QueryTime: time.Now()}, Time: time.Now()},
+				})
+				path := filepath.Join(dir, resolveJournalFile)
+				content, _ := os.ReadFile(path)
+				lines := bytes.Split(bytes.TrimSpace(content), []byte("\n"))
+				forged, _ := json.Marshal(&sealedJournalLine{Sealed: bytes.Repeat([]byte{7}, 64)})
+				lines[len(lines)-1] = forged
+				os.WriteFile(path, append(bytes.Join(lines, []byte("\n")), '\n'), 0600)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			tt.prepare(t, dir)
+
+			data, err := newEncryptedTestStore(t, dir, 1).LoadResolveCache()
+			if !errors.Is(err, ErrCorruptCache) || data != nil {
+				t.Fatalf("LoadResolveCache() = %+v, %v, want ErrCorruptCache", data, err)
+			}
+			if quarantined, _ := filepath.Glob(filepath.Join(dir, "*.corrupt-*")); len(quarantined) != 1 {
+				t.Errorf("quarantined files = %v, want 1", quarantined)
+			}
+		})
+	}
+}
+
+func TestConfig_CacheEncryption(t *testing.T) {
+	config := DefaultConfig()
+	config.AccountID = "test123"
+	config.EncryptCacheWithSecretKey = true
+	if err := config.Validate(); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Validate() without SecretKey error = %v, want ErrInvalidConfig", err)
+	}
+
+	config.SecretKey = "secret"
+	config.EnablePersistentCache = true
+	config.CacheDir = t.TempDir()
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	cm := NewCacheManager(config)
+	store, ok := cm.store.(*FileStore)
+	if !ok || store.keys == nil {
+		t.Fatalf("store = %+v, want encrypted FileStore", cm.store)
+	}
+
+	cm.Set("example.com", &CacheEntry{IPv4: []string{"1.2.3.4"}, TTL: 60, QueryTime: time.Now()})
+	cm.SavePending()
+
+	cm2 := NewCacheManager(config)
+	if err := cm2.LoadFromDisk(); err != nil {
+		t.Fatalf("LoadFromDisk() error = %v", err)
+	}
+	if _, hit, _ := cm2.Get("example.com"); !hit {
+		t.Error("Get() should hit after loading encrypted cache")
+	}
+}
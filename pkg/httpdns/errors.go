@@ -1,18 +1,40 @@
 package httpdns
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
 )
 
 // 定义具体的错误类型
 var (
-	ErrInvalidConfig      = errors.New("invalid configuration")
-	ErrAuthFailed         = errors.New("authentication failed")
-	ErrNetworkTimeout     = errors.New("network timeout")
-	ErrInvalidDomain      = errors.New("invalid domain name")
-	ErrServiceUnavailable = errors.New("service unavailable")
-	ErrTooManyDomains     = errors.New("too many domains, maximum 5 domains allowed per batch request")
+	ErrInvalidConfig       = errors.New("invalid configuration")
+	ErrAuthFailed          = errors.New("authentication failed")
+	ErrNetworkTimeout      = errors.New("network timeout")
+	ErrInvalidDomain       = errors.New("invalid domain name")
+	ErrServiceUnavailable  = errors.New("service unavailable")
+	ErrNoResult            = errors.New("no result returned for domain")
+	ErrNoAddresses         = errors.New("no addresses returned for domain")
+	ErrCacheDisabled       = errors.New("memory cache is disabled")
+	ErrInvalidSnapshot     = errors.New("invalid or unsupported cache snapshot")
+	ErrCorruptCache        = errors.New("persistent cache file corrupted")
+	ErrUnsupportedCache    = errors.New("persistent cache format version not supported")
+	ErrCacheKeyUnavailable = errors.New("persistent cache encryption key unavailable")
+	ErrClockSkew           = errors.New("signature rejected due to clock skew")
+
+	// 服务端错误码对应的错误，可用 errors.Is 判断，同时匹配所属分类（如 ErrAuthFailed）
+	ErrInvalidAccount   = errors.New("invalid account")
+	ErrInvalidSignature = errors.New("invalid signature")
+	ErrSignatureExpired = errors.New("signature expired")
+	ErrMethodNotAllowed = errors.New("method not allowed")
+	ErrRateLimited      = errors.New("request rate limited")
+	ErrBadRequest       = errors.New("bad request")
+	// Deprecated: ResolveBatch 会自动按 Config.MaxBatchDomains 拆分请求，不再返回该错误
+	ErrTooManyDomains = errors.New("too many domains, maximum 5 domains allowed per batch request")
 )
 
 // HTTPDNSError 包装错误信息
@@ -41,3 +63,150 @@ func NewHTTPDNSError(op, domain string, err error) *HTTPDNSError {
 		Err:    err,
 	}
 }
+
+// serverErrorKind 服务端错误码的具体错误和所属分类
+type serverErrorKind struct {
+	err      error
+	category error
+}
+
+// serverErrorCatalog 服务端错误码目录
+var serverErrorCatalog = map[string]serverErrorKind{
+	"InvalidAccount":   {ErrInvalidAccount, ErrAuthFailed},
+	"AccountNotExists": {ErrInvalidAccount, ErrAuthFailed},
+	"InvalidSignature": {ErrInvalidSignature, ErrAuthFailed},
+	"SignatureExpired": {ErrSignatureExpired, ErrAuthFailed},
+	"InvalidTimestamp": {ErrSignatureExpired, ErrAuthFailed},
+	"MethodNotAllowed": {ErrMethodNotAllowed, ErrBadRequest},
+	"InvalidHost":      {ErrInvalidDomain, ErrBadRequest},
+	"InvalidDuration":  {ErrBadRequest, ErrBadRequest},
+	"TooManyRequests":  {ErrRateLimited, ErrRateLimited},
+}
+
+// maxErrorBodySize 解析错误响应时读取的最大字节数
+const maxErrorBodySize = 4 << 10
+
+// ServerError 服务端返回的非 200 响应
+type ServerError struct {
+	StatusCode int    // HTTP 状态码
+	Code       string // 服务端错误码，响应体无法解析时为空
+	kind       serverErrorKind
+}
+
+// newServerError 读取响应体中的错误码并按目录归类，未知错误码按状态码归类
+func newServerError(resp *http.Response) *ServerError {
+	e := &ServerError{StatusCode: resp.StatusCode}
+
+	var body struct {
+		Code string `json:"code"`
+	}
+	if data, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize)); err == nil && json.Unmarshal(data, &body) == nil {
+		e.Code = body.Code
+	}
+
+	if kind, ok := serverErrorCatalog[e.Code]; ok {
+		e.kind = kind
+		return e
+	}
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		e.kind = serverErrorKind{ErrAuthFailed, ErrAuthFailed}
+	case resp.StatusCode == http.StatusTooManyRequests:
+		e.kind = serverErrorKind{ErrRateLimited, ErrRateLimited}
+	case resp.StatusCode == http.StatusMethodNotAllowed:
+		e.kind = serverErrorKind{ErrMethodNotAllowed, ErrBadRequest}
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		e.kind = serverErrorKind{ErrBadRequest, ErrBadRequest}
+	default:
+		e.kind = serverErrorKind{ErrServiceUnavailable, ErrServiceUnavailable}
+	}
+	return e
+}
+
+func (e *ServerError) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("HTTP %d %s: %v", e.StatusCode, e.Code, e.kind.err)
+	}
+	return fmt.Sprintf("HTTP %d: %v", e.StatusCode, e.kind.err)
+}
+
+// Unwrap 返回具体错误和所属分类
+func (e *ServerError) Unwrap() []error {
+	if e.kind.err == e.kind.category {
+		return []error{e.kind.err}
+	}
+	return []error{e.kind.err, e.kind.category}
+}
+
+// Retryable 是否可以换服务IP重试，仅服务端故障可重试，鉴权、请求错误和限流不重试
+func (e *ServerError) Retryable() bool {
+	return e.kind.category == ErrServiceUnavailable
+}
+
+// RequestAttempt 单次请求尝试的详情
+type RequestAttempt struct {
+	Attempt    int           // 尝试序号，从1开始
+	ServiceIP  string        // 请求的服务IP，构建URL失败时为空；对冲时为返回结果的服务IP
+	Hedged     bool          // 结果是否来自对冲请求
+	Duration   time.Duration // 本次尝试耗时
+	Timeout    time.Duration // 本次尝试的超时，构建URL失败时为0
+	StatusCode int           // HTTP 状态码，未收到响应时为0
+	Err        error         // 失败原因，成功时为 nil
+}
+
+func (a RequestAttempt) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "attempt %d", a.Attempt)
+	if a.ServiceIP != "" {
+		fmt.Fprintf(&b, " to %s", a.ServiceIP)
+	}
+	if a.Hedged {
+		b.WriteString(" (hedged)")
+	}
+	if a.StatusCode != 0 {
+		fmt.Fprintf(&b, " HTTP %d", a.StatusCode)
+	}
+	fmt.Fprintf(&b, " (%v)", a.Duration)
+	if a.Err != nil {
+		fmt.Fprintf(&b, ": %v", a.Err)
+	}
+	return b.String()
+}
+
+// RetryError 请求的所有尝试均失败，按顺序记录每次尝试
+type RetryError struct {
+	Attempts []RequestAttempt
+	Cause    error // 重试等待被中断的原因（如 ctx 超时或取消），未中断时为 nil
+}
+
+func (e *RetryError) Error() string {
+	parts := make([]string, len(e.Attempts))
+	for i, attempt := range e.Attempts {
+		parts[i] = attempt.String()
+	}
+	msg := fmt.Sprintf("%d attempts failed: %s", len(e.Attempts), strings.Join(parts, "; "))
+	if e.Cause != nil {
+		msg += fmt.Sprintf("; retry interrupted: %v", e.Cause)
+	}
+	return msg
+}
+
+// Unwrap 返回每次尝试的失败原因及重试中断的原因
+func (e *RetryError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Attempts)+1)
+	for _, attempt := range e.Attempts {
+		errs = append(errs, attempt.Err)
+	}
+	if e.Cause != nil {
+		errs = append(errs, e.Cause)
+	}
+	return errs
+}
+
+// LastErr 返回最后一次尝试的失败原因
+func (e *RetryError) LastErr() error {
+	if len(e.Attempts) == 0 {
+		return nil
+	}
+	return e.Attempts[len(e.Attempts)-1].Err
+}
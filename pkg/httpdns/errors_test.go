@@ -1,8 +1,16 @@
 package httpdns
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestHTTPDNSError_Error(t *testing.T) {
@@ -99,3 +107,196 @@ func TestErrorTypes(t *testing.T) {
 		})
 	}
 }
+
+func TestNewServerError(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		body      string
+		want      []error
+		retryable bool
+	}{
+		{"invalid account", http.StatusForbidden, `{"code":"InvalidAccount"}`, []error{ErrInvalidAccount, ErrAuthFailed}, false},
+		{"invalid signature", http.StatusForbidden, `{"code":"InvalidSignature"}`, []error{ErrInvalidSignature, ErrAuthFailed}, false},
+		{"signature expired", http.StatusForbidden, `{"code":"SignatureExpired"}`, []error{ErrSignatureExpired, ErrAuthFailed}, false},
+		{"method not allowed", http.StatusMethodNotAllowed, `{"code":"MethodNotAllowed"}`, []error{ErrMethodNotAllowed, ErrBadRequest}, false},
+		{"invalid host", http.StatusBadRequest, `{"code":"InvalidHost"}`, []error{ErrInvalidDomain, ErrBadRequest}, false},
+		{"unknown forbidden", http.StatusForbidden, `forbidden`, []error{ErrAuthFailed}, false},
+		{"rate limited", http.StatusTooManyRequests, ``, []error{ErrRateLimited}, false},
+		{"server error", http.StatusBadGateway, `<html></html>`, []error{ErrServiceUnavailable}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newServerError(&http.Response{StatusCode: tt.status, Body: io.NopCloser(strings.NewReader(tt.body))})
+			for _, want := range tt.want {
+				if !errors.Is(err, want) {
+					t.Errorf("errors.Is(%v, %v) = false, want true", err, want)
+				}
+			}
+			if err.Retryable() != tt.retryable {
+				t.Errorf("Retryable() = %v, want %v", err.Retryable(), tt.retryable)
+			}
+			if err.StatusCode != tt.status {
+				t.Errorf("StatusCode = %d, want %d", err.StatusCode, tt.status)
+			}
+		})
+	}
+}
+
+func TestResolver_ServerErrorRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		code         string
+		wantRequests int32
+		wantErr      error
+		wantAuth     int64
+		wantNetwork  int64
+	}{
+		{"auth failure not retried", http.StatusForbidden, "InvalidAccount", 1, ErrInvalidAccount, 1, 0},
+		{"server failure retried", http.StatusServiceUnavailable, "", 2, ErrServiceUnavailable, 0, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				if strings.HasSuffix(r.URL.Path, "/ss") {
+					json.NewEncoder(w).Encode(map[string]interface{}{"service_ip": []string{r.Host}})
+					return
+				}
+				atomic.AddInt32(&requests, 1)
+				w.WriteHeader(tt.status)
+				json.NewEncoder(w).Encode(map[string]string{"code": tt.code})
+			}))
+			defer server.Close()
+
+			config := DefaultConfig()
+			config.AccountID = "test123"
+			config.BootstrapIPs = []string{server.URL[7:]}
+			config.MaxRetries = 1
+			config.EnableMemoryCache = false
+			config.EnableMetrics = true
+			resolver := NewResolver(config)
+			resolver.httpClient.serviceIPManager.UpdateServiceIPs([]string{server.URL[7:]})
+
+			_, err := resolver.ResolveSingle(context.Background(), "example.com")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ResolveSingle() error = %v, want %v", err, tt.wantErr)
+			}
+			stats := resolver.GetMetrics()
+			if stats.AuthErrors != tt.wantAuth || stats.NetworkErrors != tt.wantNetwork {
+				t.Errorf("AuthErrors = %d, NetworkErrors = %d, want %d, %d", stats.AuthErrors, stats.NetworkErrors, tt.wantAuth, tt.wantNetwork)
+			}
+			if got := atomic.LoadInt32(&requests); got != tt.wantRequests {
+				t.Errorf("requests = %d, want %d", got, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestRetryError(t *testing.T) {
+	authErr := NewHTTPDNSError("http_status", "", &ServerError{StatusCode: http.StatusForbidden, Code: "InvalidAccount", kind: serverErrorCatalog["InvalidAccount"]})
+	err := &RetryError{Attempts: []RequestAttempt{
+		{Attempt: 1, ServiceIP: "1.1.1.1", StatusCode: http.StatusBadGateway, Err: ErrServiceUnavailable},
+		{Attempt: 2, ServiceIP: "2.2.2.2", StatusCode: http.StatusForbidden, Err: authErr},
+	}}
+
+	for _, want := range []error{ErrServiceUnavailable, ErrInvalidAccount, ErrAuthFailed} {
+		if !errors.Is(err, want) {
+			t.Errorf("errors.Is(RetryError, %v) = false, want true", want)
+		}
+	}
+	if err.LastErr() != authErr {
+		t.Errorf("LastErr() = %v, want %v", err.LastErr(), authErr)
+	}
+	msg := err.Error()
+	for _, want := range []string{"2 attempts failed", "attempt 1 to 1.1.1.1 HTTP 502", "attempt 2 to 2.2.2.2 HTTP 403"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("Error() = %q, want it to contain %q", msg, want)
+		}
+	}
+}
+
+func TestHTTPDNSClient_DoRequestWithRetry_Attempts(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	var hooked []RequestAttempt
+	config := DefaultConfig()
+	config.AccountID = "test123"
+	config.MaxRetries = 1
+	config.OnRequestAttempt = func(attempt RequestAttempt) {
+		hooked = append(hooked, attempt)
+	}
+	client := NewHTTPDNSClient(config)
+
+	_, err := client.DoRequestWithRetry(context.Background(), func() (string, error) {
+		return server.URL + "/test123/d?host=example.com", nil
+	})
+
+	var retryErr *RetryError
+	if !errors.As(err, &retryErr) {
+		t.Fatalf("DoRequestWithRetry() error = %v, want RetryError", err)
+	}
+	if len(retryErr.Attempts) != 2 {
+		t.Fatalf("Attempts = %d, want 2", len(retryErr.Attempts))
+	}
+	for i, attempt := range retryErr.Attempts {
+		if attempt.Attempt != i+1 || attempt.ServiceIP != server.URL[7:] || attempt.StatusCode != http.StatusServiceUnavailable {
+			t.Errorf("Attempts[%d] = %+v, want attempt %d to %s with HTTP 503", i, attempt, i+1, server.URL[7:])
+		}
+		if attempt.Duration <= 0 || !errors.Is(attempt.Err, ErrServiceUnavailable) {
+			t.Errorf("Attempts[%d] duration = %v, err = %v", i, attempt.Duration, attempt.Err)
+		}
+	}
+	if len(hooked) != 2 || hooked[1].Attempt != 2 || hooked[1].Err == nil {
+		t.Errorf("OnRequestAttempt calls = %+v, want the 2 failed attempts", hooked)
+	}
+	if got := atomic.LoadInt32(&requests); got != 2 {
+		t.Errorf("requests = %d, want 2", got)
+	}
+}
+
+func TestHTTPDNSClient_DoRequestWithPolicy_BackoffInterrupted(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	config := DefaultConfig()
+	config.AccountID = "test123"
+	client := NewHTTPDNSClient(config)
+
+	// 整体超时在重试等待期间到期，仍返回已有尝试的详情
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	policy := &ExponentialBackoff{MaxRetries: 3, BaseDelay: time.Second}
+	_, err := client.DoRequestWithPolicy(ctx, policy, func() (string, error) {
+		return server.URL + "/test123/d?host=example.com", nil
+	})
+
+	var retryErr *RetryError
+	if !errors.As(err, &retryErr) {
+		t.Fatalf("DoRequestWithPolicy() error = %v, want RetryError", err)
+	}
+	if len(retryErr.Attempts) != 1 || retryErr.Attempts[0].StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Attempts = %+v, want the failed attempt before backoff", retryErr.Attempts)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) || !errors.Is(retryErr.Cause, context.DeadlineExceeded) {
+		t.Errorf("error = %v, want deadline exceeded as cause", err)
+	}
+	if !errors.Is(err, ErrServiceUnavailable) {
+		t.Errorf("error = %v, want attempt errors to be preserved", err)
+	}
+	var httpDNSErr *HTTPDNSError
+	if !errors.As(err, &httpDNSErr) || httpDNSErr.Op != "request_retry_failed" {
+		t.Errorf("error = %v, want request_retry_failed", err)
+	}
+}
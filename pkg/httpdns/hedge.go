@@ -0,0 +1,255 @@
+package httpdns
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 对冲请求参数
+const (
+	DefaultHedgeDelay  = 100 * time.Millisecond // 延迟样本不足时的对冲延迟
+	DefaultHedgeBudget = 0.1                    // 默认对冲请求数不超过请求数的10%
+
+	hedgeLatencySamples    = 128 // 计算 p95 使用的最近成功请求数
+	hedgeMinLatencySamples = 20  // 样本数达到该值后使用 p95
+	hedgeMinDelay          = 10 * time.Millisecond
+	hedgeBudgetBurst       = 10 // 预算累积上限，允许短时突发
+)
+
+// hedger 对冲请求的延迟估计和预算控制
+type hedger struct {
+	mutex     sync.Mutex
+	latencies []time.Duration // 最近成功请求的响应延迟（环形缓冲）
+	next      int
+	tokens    float64 // 可用的对冲次数，每个请求增加 HedgeBudget，每次对冲消耗1
+	hedged    int64   // 发出的对冲请求数
+	wins      int64   // 对冲请求先于首个请求成功的次数
+}
+
+// observe 记录一次成功请求的响应延迟
+func (h *hedger) observe(latency time.Duration) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if len(h.latencies) < hedgeLatencySamples {
+		h.latencies = append(h.latencies, latency)
+		return
+	}
+	h.latencies[h.next] = latency
+	h.next = (h.next + 1) % hedgeLatencySamples
+}
+
+// delay 返回对冲延迟：配置了固定延迟时直接使用，否则为最近成功请求延迟的 p95
+func (h *hedger) delay(fixed time.Duration) time.Duration {
+	if fixed > 0 {
+		return fixed
+	}
+
+	h.mutex.Lock()
+	if len(h.latencies) < hedgeMinLatencySamples {
+		h.mutex.Unlock()
+		return DefaultHedgeDelay
+	}
+	sorted := append([]time.Duration(nil), h.latencies...)
+	h.mutex.Unlock()
+
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	p95 := sorted[len(sorted)*95/100]
+	if p95 < hedgeMinDelay {
+		p95 = hedgeMinDelay
+	}
+	return p95
+}
+
+// earn 每个请求按预算比例累积对冲次数
+func (h *hedger) earn(budget float64) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.tokens += budget
+	if h.tokens > hedgeBudgetBurst {
+		h.tokens = hedgeBudgetBurst
+	}
+}
+
+// allow 预算充足时消耗一次对冲次数
+func (h *hedger) allow() bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.tokens < 1 {
+		return false
+	}
+	h.tokens--
+	h.hedged++
+	return true
+}
+
+// refund 没有发出对冲请求时退还 allow 消耗的对冲次数
+func (h *hedger) refund() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.tokens++
+	h.hedged--
+}
+
+// win 记录一次对冲请求胜出
+func (h *hedger) win() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.wins++
+}
+
+// stats 返回发出的对冲请求数和胜出次数
+func (h *hedger) stats() (hedged, wins int64) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return h.hedged, h.wins
+}
+
+// hedgeResult 对冲中单个请求的结果
+type hedgeResult struct {
+	resp      *http.Response
+	serviceIP string
+	hedged    bool
+	err       error
+	index     int // 请求序号，对应 doHedgedRequest 中的 cancels
+	cancel    context.CancelFunc
+}
+
+// ok 是否为成功响应
+func (r *hedgeResult) ok() bool {
+	return r.err == nil && r.resp.StatusCode == http.StatusOK
+}
+
+// discard 取消请求并关闭响应体
+func (r *hedgeResult) discard() {
+	r.cancel()
+	if r.resp != nil {
+		r.resp.Body.Close()
+	}
+}
+
+// release 将结果交给调用方：有响应时在响应体关闭后取消请求，否则立即取消
+func (r *hedgeResult) release() *hedgeResult {
+	if r.resp != nil {
+		r.resp.Body = &cancelOnClose{ReadCloser: r.resp.Body, cancel: r.cancel}
+	} else {
+		r.cancel()
+	}
+	return r
+}
+
+// doHedgedRequest 执行单次尝试：启用对冲时，首个请求在对冲延迟内未响应则向另一个服务IP发送相同请求，
+// 使用最先成功的响应并取消其他请求；都失败时返回首个失败的结果
+func (c *HTTPDNSClient) doHedgedRequest(ctx context.Context, rawURL string, timeout time.Duration) *hedgeResult {
+	primaryIP := extractServiceIPFromURL(rawURL)
+	if !c.config.EnableHedging {
+		resp, err := c.doRequest(ctx, rawURL, timeout)
+		return &hedgeResult{resp: resp, serviceIP: primaryIP, err: err, cancel: func() {}}
+	}
+
+	c.hedge.earn(c.config.HedgeBudget)
+	deadline := time.Now().Add(timeout)
+	results := make(chan *hedgeResult, 2)
+	var cancels []context.CancelFunc
+	start := func(target, serviceIP string, hedged bool) {
+		reqCtx, cancel := context.WithCancel(ctx)
+		index := len(cancels)
+		cancels = append(cancels, cancel)
+		go func() {
+			sentAt := time.Now()
+			resp, err := c.doRequest(reqCtx, target, time.Until(deadline))
+			result := &hedgeResult{resp: resp, serviceIP: serviceIP, hedged: hedged, err: err, index: index, cancel: cancel}
+			if result.ok() {
+				c.hedge.observe(time.Since(sentAt))
+			}
+			results <- result
+		}()
+	}
+
+	start(rawURL, primaryIP, false)
+	inflight := 1
+	timer := time.NewTimer(c.hedge.delay(c.config.HedgeDelay))
+	defer timer.Stop()
+
+	var failed *hedgeResult
+	for {
+		select {
+		case <-timer.C:
+			// 先消耗预算再选择服务IP：选择可能将熔断到期的IP转为半开探测，必须确保随后真正发出请求
+			if !c.hedge.allow() {
+				continue
+			}
+			alt, ok := c.serviceIPManager.GetAlternativeIP(primaryIP)
+			if !ok {
+				c.hedge.refund()
+				continue
+			}
+			target, err := replaceURLHost(rawURL, alt)
+			if err != nil {
+				c.hedge.refund()
+				c.serviceIPManager.ReleaseProbe(alt)
+				continue
+			}
+			start(target, alt, true)
+			inflight++
+		case result := <-results:
+			inflight--
+			if result.ok() {
+				if result.hedged {
+					c.hedge.win()
+				}
+				if failed != nil {
+					failed.discard()
+				}
+				for i, cancel := range cancels {
+					if i != result.index {
+						cancel()
+					}
+				}
+				go drainHedgeResults(results, inflight)
+				return result.release()
+			}
+			if failed == nil {
+				failed = result
+			} else {
+				result.discard()
+			}
+			// 所有请求都已失败（首个请求在对冲前失败时直接返回，由重试策略处理）
+			if inflight == 0 {
+				return failed.release()
+			}
+		}
+	}
+}
+
+// drainHedgeResults 回收已取消的其他请求
+func drainHedgeResults(results <-chan *hedgeResult, inflight int) {
+	for i := 0; i < inflight; i++ {
+		result := <-results
+		result.discard()
+	}
+}
+
+// replaceURLHost 将请求URL的主机替换为另一个服务IP（签名与服务IP无关）
+func replaceURLHost(rawURL, serviceIP string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	u.Host = serviceIP
+	return u.String(), nil
+}
+
+// HedgeStats 返回发出的对冲请求数和对冲请求胜出次数
+func (c *HTTPDNSClient) HedgeStats() (hedged, wins int64) {
+	return c.hedge.stats()
+}
@@ -0,0 +1,154 @@
+package httpdns
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHedger(t *testing.T) {
+	h := &hedger{}
+
+	if got := h.delay(50 * time.Millisecond); got != 50*time.Millisecond {
+		t.Errorf("delay(fixed) = %v, want 50ms", got)
+	}
+	if got := h.delay(0); got != DefaultHedgeDelay {
+		t.Errorf("delay() without samples = %v, want %v", got, DefaultHedgeDelay)
+	}
+	for i := 1; i <= 100; i++ {
+		h.observe(time.Duration(i) * time.Millisecond)
+	}
+	if got := h.delay(0); got != 96*time.Millisecond {
+		t.Errorf("delay() p95 = %v, want 96ms", got)
+	}
+
+	// 每个请求累积 0.5 次，两个请求后才允许一次对冲
+	h.earn(0.5)
+	if h.allow() {
+		t.Error("allow() = true before budget is earned")
+	}
+	h.earn(0.5)
+	if !h.allow() || h.allow() {
+		t.Error("allow() should permit exactly one hedge")
+	}
+	for i := 0; i < 100; i++ {
+		h.earn(1)
+	}
+	if h.tokens != hedgeBudgetBurst {
+		t.Errorf("tokens = %v, want capped at %v", h.tokens, hedgeBudgetBurst)
+	}
+	if hedged, _ := h.stats(); hedged != 1 {
+		t.Errorf("stats() hedged = %d, want 1", hedged)
+	}
+
+	// 没有发出对冲请求时退还
+	if !h.allow() {
+		t.Fatal("allow() = false with tokens available")
+	}
+	h.refund()
+	if hedged, _ := h.stats(); hedged != 1 || h.tokens != hedgeBudgetBurst {
+		t.Errorf("after refund hedged = %d, tokens = %v, want 1 and %v", hedged, h.tokens, hedgeBudgetBurst)
+	}
+}
+
+func TestHTTPDNSClient_HedgingWithoutAlternative(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		json.NewEncoder(w).Encode(HTTPDNSResponse{Host: "example.com"})
+	}))
+	defer server.Close()
+
+	config := DefaultConfig()
+	config.AccountID = "test123"
+	config.EnableHedging = true
+	config.HedgeDelay = 10 * time.Millisecond
+	config.HedgeBudget = 1
+	client := NewHTTPDNSClient(config)
+	client.serviceIPManager.UpdateServiceIPs([]string{server.URL[7:]})
+
+	// 只有一个服务IP时不发出对冲请求，预算不被消耗
+	result := client.doHedgedRequest(context.Background(), server.URL+"/test123/d?host=example.com", time.Second)
+	if !result.ok() {
+		t.Fatalf("doHedgedRequest() error = %v", result.err)
+	}
+	result.resp.Body.Close()
+	if hedged, _ := client.HedgeStats(); hedged != 0 || client.hedge.tokens < 1 {
+		t.Errorf("HedgeStats() hedged = %d, tokens = %v, want no hedge and budget kept", hedged, client.hedge.tokens)
+	}
+}
+
+func TestHTTPDNSClient_Hedging(t *testing.T) {
+	canceled := make(chan struct{}, 1)
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(300 * time.Millisecond):
+			json.NewEncoder(w).Encode(HTTPDNSResponse{Host: "slow"})
+		case <-r.Context().Done():
+			canceled <- struct{}{}
+		}
+	}))
+	defer slow.Close()
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(HTTPDNSResponse{Host: "fast"})
+	}))
+	defer fast.Close()
+
+	tests := []struct {
+		name       string
+		budget     float64
+		wantHost   string
+		wantHedged int64
+	}{
+		{"hedge to faster service IP", 1, "fast", 1},
+		{"budget exhausted", DefaultHedgeBudget, "slow", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts []RequestAttempt
+			config := DefaultConfig()
+			config.AccountID = "test123"
+			config.EnableHedging = true
+			config.HedgeDelay = 20 * time.Millisecond
+			config.HedgeBudget = tt.budget
+			config.OnRequestAttempt = func(attempt RequestAttempt) {
+				attempts = append(attempts, attempt)
+			}
+			client := NewHTTPDNSClient(config)
+			client.serviceIPManager.UpdateServiceIPs([]string{slow.URL[7:], fast.URL[7:]})
+
+			resp, err := client.DoRequestWithRetry(context.Background(), func() (string, error) {
+				serviceIP, err := client.GetAvailableServiceIP()
+				return "http://" + serviceIP + "/test123/d?host=example.com", err
+			})
+			if err != nil {
+				t.Fatalf("DoRequestWithRetry() error = %v", err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+
+			var dnsResp HTTPDNSResponse
+			if err := json.Unmarshal(body, &dnsResp); err != nil || dnsResp.Host != tt.wantHost {
+				t.Errorf("response host = %q (%v), want %q", dnsResp.Host, err, tt.wantHost)
+			}
+			if hedged, wins := client.HedgeStats(); hedged != tt.wantHedged || wins != tt.wantHedged {
+				t.Errorf("HedgeStats() = %d, %d, want %d, %d", hedged, wins, tt.wantHedged, tt.wantHedged)
+			}
+			if len(attempts) != 1 || attempts[0].Hedged != (tt.wantHedged > 0) {
+				t.Errorf("attempts = %+v, want one attempt with Hedged = %v", attempts, tt.wantHedged > 0)
+			}
+
+			// 对冲胜出后首个请求被取消
+			if tt.wantHedged > 0 {
+				select {
+				case <-canceled:
+				case <-time.After(time.Second):
+					t.Error("slow request was not canceled after hedge won")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly || windows)
+
+package httpdns
+
+import "os"
+
+// lockFile 当前平台不支持文件锁，仅依赖进程内互斥
+func lockFile(f *os.File, exclusive bool) error {
+	return nil
+}
+
+// unlockFile 当前平台不支持文件锁
+func unlockFile(f *os.File) error {
+	return nil
+}
@@ -1,6 +1,8 @@
 package httpdns
 
 import (
+	"context"
+	"errors"
 	"sync"
 	"time"
 )
@@ -13,6 +15,16 @@ type Metrics struct {
 	FailedResolves  int64 // 失败解析次数
 	CacheHits       int64 // 缓存命中次数（当前实现中未使用缓存）
 
+	// 负缓存统计
+	NegativeCacheHits int64 // 负缓存命中次数
+
+	// 异常结果统计
+	AnswerAnomalies int64 // 空结果或部分结果次数
+	AnswersRejected int64 // 因保护策略未写入缓存的异常结果次数
+
+	// 持久化统计
+	CorruptCacheFiles int64 // 被隔离的损坏持久化文件数
+
 	// 延迟统计
 	TotalLatency time.Duration // 总延迟时间
 	MinLatency   time.Duration // 最小延迟
@@ -74,38 +86,87 @@ func (m *Metrics) RecordAPIRequest(success bool, responseTime time.Duration) {
 	}
 }
 
-// RecordError 记录错误
+// networkErrorOps 归类为网络错误的操作
+var networkErrorOps = map[string]bool{
+	"http_request":         true,
+	"http_status":          true,
+	"request_retry_failed": true,
+	"fetch_service_ips":    true,
+}
+
+// RecordError 记录错误，按错误链中的错误类型和操作归类
 func (m *Metrics) RecordError(err error) {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
-	if httpDNSErr, ok := err.(*HTTPDNSError); ok {
-		switch httpDNSErr.Op {
-		case "http_request", "request_retry_failed":
-			m.NetworkErrors++
-		case "auth_failed":
-			m.AuthErrors++
-		case "validate_domain":
-			m.ValidationErrors++
+	switch {
+	case errors.Is(err, ErrAuthFailed):
+		m.AuthErrors++
+	case errors.Is(err, ErrInvalidDomain):
+		m.ValidationErrors++
+	case errors.Is(err, ErrNetworkTimeout), errors.Is(err, context.DeadlineExceeded), hasErrorOp(err, networkErrorOps):
+		m.NetworkErrors++
+	}
+}
+
+// hasErrorOp 判断错误链中是否有操作名在 ops 中的 HTTPDNSError
+func hasErrorOp(err error, ops map[string]bool) bool {
+	var httpDNSErr *HTTPDNSError
+	for errors.As(err, &httpDNSErr) {
+		if ops[httpDNSErr.Op] {
+			return true
 		}
+		err = httpDNSErr.Err
+	}
+	return false
+}
+
+// RecordNegativeCacheHit 记录负缓存命中
+func (m *Metrics) RecordNegativeCacheHit() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.NegativeCacheHits++
+}
+
+// RecordAnswerAnomaly 记录一次异常结果，rejected 表示是否保留了原有缓存
+func (m *Metrics) RecordAnswerAnomaly(rejected bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.AnswerAnomalies++
+	if rejected {
+		m.AnswersRejected++
 	}
 }
 
+// RecordCacheCorruption 记录一次损坏的持久化文件
+func (m *Metrics) RecordCacheCorruption() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.CorruptCacheFiles++
+}
+
 // GetStats 获取统计信息
 func (m *Metrics) GetStats() MetricsStats {
 	m.mutex.RLock()
 	defer m.mutex.RUnlock()
 
 	stats := MetricsStats{
-		TotalResolves:    m.TotalResolves,
-		SuccessResolves:  m.SuccessResolves,
-		FailedResolves:   m.FailedResolves,
-		CacheHits:        m.CacheHits,
-		APIRequests:      m.APIRequests,
-		APIErrors:        m.APIErrors,
-		NetworkErrors:    m.NetworkErrors,
-		AuthErrors:       m.AuthErrors,
-		ValidationErrors: m.ValidationErrors,
+		TotalResolves:     m.TotalResolves,
+		SuccessResolves:   m.SuccessResolves,
+		FailedResolves:    m.FailedResolves,
+		CacheHits:         m.CacheHits,
+		NegativeCacheHits: m.NegativeCacheHits,
+		AnswerAnomalies:   m.AnswerAnomalies,
+		AnswersRejected:   m.AnswersRejected,
+		CorruptCacheFiles: m.CorruptCacheFiles,
+		APIRequests:       m.APIRequests,
+		APIErrors:         m.APIErrors,
+		NetworkErrors:     m.NetworkErrors,
+		AuthErrors:        m.AuthErrors,
+		ValidationErrors:  m.ValidationErrors,
 	}
 
 	// 计算成功率
@@ -139,6 +200,10 @@ func (m *Metrics) Reset() {
 	m.SuccessResolves = 0
 	m.FailedResolves = 0
 	m.CacheHits = 0
+	m.NegativeCacheHits = 0
+	m.AnswerAnomalies = 0
+	m.AnswersRejected = 0
+	m.CorruptCacheFiles = 0
 	m.TotalLatency = 0
 	m.MinLatency = time.Duration(^uint64(0) >> 1)
 	m.MaxLatency = 0
@@ -159,6 +224,24 @@ type MetricsStats struct {
 	CacheHits       int64   `json:"cache_hits"`
 	SuccessRate     float64 `json:"success_rate"`
 
+	// 负缓存统计
+	NegativeCacheHits    int64 `json:"negative_cache_hits"`
+	NegativeCacheEntries int64 `json:"negative_cache_entries"` // 当前生效的负缓存条目数
+
+	// 异常结果统计
+	AnswerAnomalies int64 `json:"answer_anomalies"`
+	AnswersRejected int64 `json:"answers_rejected"`
+
+	// 持久化统计
+	CorruptCacheFiles int64 `json:"corrupt_cache_files"`
+
+	// 对冲请求统计
+	HedgedRequests int64 `json:"hedged_requests"` // 发出的对冲请求数
+	HedgeWins      int64 `json:"hedge_wins"`      // 对冲请求先于首个请求成功的次数
+
+	// 时钟统计
+	ClockSkew time.Duration `json:"clock_skew"` // 估算的服务端时间 - 本地时间，签名时自动补偿
+
 	// 延迟统计
 	AvgLatency time.Duration `json:"avg_latency"`
 	MinLatency time.Duration `json:"min_latency"`
@@ -184,12 +267,51 @@ type MetricsCollector interface {
 	Reset()
 }
 
+// NegativeCacheMetrics 负缓存指标（可选接口），MetricsCollector 实现该接口时记录负缓存命中
+type NegativeCacheMetrics interface {
+	RecordNegativeCacheHit()
+}
+
+// recordNegativeCacheHit 收集器支持时记录负缓存命中
+func recordNegativeCacheHit(m MetricsCollector) {
+	if nm, ok := m.(NegativeCacheMetrics); ok {
+		nm.RecordNegativeCacheHit()
+	}
+}
+
+// AnswerAnomalyMetrics 异常结果指标（可选接口），MetricsCollector 实现该接口时记录异常结果
+type AnswerAnomalyMetrics interface {
+	RecordAnswerAnomaly(rejected bool)
+}
+
+// recordAnswerAnomaly 收集器支持时记录异常结果
+func recordAnswerAnomaly(m MetricsCollector, rejected bool) {
+	if am, ok := m.(AnswerAnomalyMetrics); ok {
+		am.RecordAnswerAnomaly(rejected)
+	}
+}
+
+// CacheCorruptionMetrics 持久化文件损坏指标（可选接口），MetricsCollector 实现该接口时记录损坏的文件
+type CacheCorruptionMetrics interface {
+	RecordCacheCorruption()
+}
+
+// recordCacheCorruption 收集器支持时记录损坏的持久化文件
+func recordCacheCorruption(m MetricsCollector) {
+	if cm, ok := m.(CacheCorruptionMetrics); ok {
+		cm.RecordCacheCorruption()
+	}
+}
+
 // NoOpMetrics 空操作指标收集器（用于禁用指标时）
 type NoOpMetrics struct{}
 
 func (n *NoOpMetrics) RecordResolve(success bool, latency time.Duration, source ResolveSource) {}
 func (n *NoOpMetrics) RecordAPIRequest(success bool, responseTime time.Duration)               {}
 func (n *NoOpMetrics) RecordError(err error)                                                   {}
+func (n *NoOpMetrics) RecordNegativeCacheHit()                                                 {}
+func (n *NoOpMetrics) RecordAnswerAnomaly(rejected bool)                                       {}
+func (n *NoOpMetrics) RecordCacheCorruption()                                                  {}
 func (n *NoOpMetrics) GetStats() MetricsStats                                                  { return MetricsStats{} }
 func (n *NoOpMetrics) Reset()                                                                  {}
 
@@ -126,12 +126,17 @@ func TestMetrics_Reset(t *testing.T) {
 	// 记录一些数据
 	metrics.RecordResolve(true, 100*time.Millisecond, SourceHTTPDNS)
 	metrics.RecordAPIRequest(true, 50*time.Millisecond)
+	metrics.RecordNegativeCacheHit()
 
 	// 重置
 	metrics.Reset()
 
 	stats := metrics.GetStats()
 
+	if stats.NegativeCacheHits != 0 {
+		t.Errorf("Reset() NegativeCacheHits = %v, want 0", stats.NegativeCacheHits)
+	}
+
 	if stats.TotalResolves != 0 {
 		t.Errorf("Reset() TotalResolves = %v, want 0", stats.TotalResolves)
 	}
@@ -196,6 +201,7 @@ func TestNoOpMetrics(t *testing.T) {
 	metrics.RecordResolve(true, 100*time.Millisecond, SourceHTTPDNS)
 	metrics.RecordAPIRequest(true, 50*time.Millisecond)
 	metrics.RecordError(ErrNetworkTimeout)
+	metrics.RecordNegativeCacheHit()
 	metrics.Reset()
 
 	stats := metrics.GetStats()
@@ -209,3 +215,27 @@ func TestNoOpMetrics(t *testing.T) {
 		t.Errorf("NoOpMetrics.GetStats() APIRequests = %v, want 0", stats.APIRequests)
 	}
 }
+
+// baseCollector 只实现 MetricsCollector 基础方法的外部收集器
+type baseCollector struct{}
+
+func (baseCollector) RecordResolve(success bool, latency time.Duration, source ResolveSource) {}
+func (baseCollector) RecordAPIRequest(success bool, responseTime time.Duration)               {}
+func (baseCollector) RecordError(err error)                                                   {}
+func (baseCollector) GetStats() MetricsStats                                                  { return MetricsStats{} }
+func (baseCollector) Reset()                                                                  {}
+
+func TestMetrics_OptionalInterfaces(t *testing.T) {
+	var collector MetricsCollector = baseCollector{}
+	recordNegativeCacheHit(collector)
+	recordAnswerAnomaly(collector, true)
+	recordCacheCorruption(collector)
+
+	metrics := NewMetrics()
+	recordNegativeCacheHit(metrics)
+	recordAnswerAnomaly(metrics, true)
+	recordCacheCorruption(metrics)
+	if stats := metrics.GetStats(); stats.NegativeCacheHits != 1 || stats.AnswerAnomalies != 1 || stats.CorruptCacheFiles != 1 {
+		t.Errorf("stats = %+v, want 1 negative cache hit, answer anomaly and corrupt file", stats)
+	}
+}
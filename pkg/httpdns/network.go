@@ -3,8 +3,11 @@ package httpdns
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
@@ -19,6 +22,8 @@ type HTTPDNSClient struct {
 	authManager      *AuthManager
 	serviceIPManager *pool.ServiceIPManager
 	bootstrapManager *pool.BootstrapManager
+	clock            *clockSkew // 根据响应 Date 头估算的服务端时钟偏差
+	hedge            *hedger    // 对冲请求的延迟估计和预算
 }
 
 // NewHTTPDNSClient 创建新的HTTP客户端
@@ -28,14 +33,24 @@ func NewHTTPDNSClient(config *Config) *HTTPDNSClient {
 		config:           config,
 		serviceIPManager: pool.NewServiceIPManager(),
 		bootstrapManager: pool.NewBootstrapManager(config.BootstrapIPs, DefaultBootstrapDomain),
+		clock:            &clockSkew{},
+		hedge:            &hedger{},
 	}
 }
 
-// SetAuthManager 设置鉴权管理器
+// SetAuthManager 设置鉴权管理器，未绑定时钟的鉴权管理器使用本客户端估算的服务端时间签名
 func (c *HTTPDNSClient) SetAuthManager(authManager *AuthManager) {
+	if authManager != nil && authManager.clock == nil {
+		authManager.clock = c.clock
+	}
 	c.authManager = authManager
 }
 
+// ClockSkew 返回估算的时钟偏差（服务端时间 - 本地时间）
+func (c *HTTPDNSClient) ClockSkew() time.Duration {
+	return c.clock.Offset()
+}
+
 // newHTTPClient 创建HTTP客户端
 func newHTTPClient(config *Config) *http.Client {
 	transport := &http.Transport{
@@ -52,9 +67,9 @@ func newHTTPClient(config *Config) *http.Client {
 		}
 	}
 
+	// 不设置整体超时，由每次请求的上下文控制（见 attemptTimeout）
 	return &http.Client{
 		Transport: transport,
-		Timeout:   config.Timeout,
 	}
 }
 
@@ -72,69 +87,104 @@ func NewRequestBuilder(config *Config, authManager *AuthManager) *RequestBuilder
 	}
 }
 
-// BuildSingleResolveURL 构建单域名解析URL
+// BuildSingleResolveURL 构建单域名解析URL，签名失败时返回空字符串（需要错误信息时使用 SingleResolveURL）
 func (b *RequestBuilder) BuildSingleResolveURL(serviceIP, domain, clientIP string, queryType QueryType) string {
+	resolveURL, _ := b.SingleResolveURL(serviceIP, domain, clientIP, queryType)
+	return resolveURL
+}
+
+// SingleResolveURL 构建单域名解析URL
+func (b *RequestBuilder) SingleResolveURL(serviceIP, domain, clientIP string, queryType QueryType) (string, error) {
 	protocol := "http"
 	if b.config.EnableHTTPS {
 		protocol = "https"
 	}
 
-	baseURL := fmt.Sprintf("%s://%s/%s", protocol, serviceIP, b.config.AccountID)
+	accountID, signParams, err := b.sign([]string{domain}, false)
+	if err != nil {
+		return "", err
+	}
+	baseURL := fmt.Sprintf("%s://%s/%s", protocol, serviceIP, accountID)
 
-	if b.authManager != nil {
+	if signParams != nil {
 		// 鉴权解析
-		timestamp, signature := b.authManager.GenerateSignature(domain)
 		if clientIP != "" {
-			return fmt.Sprintf("%s/sign_d?host=%s&query=%s&ip=%s&t=%s&s=%s",
-				baseURL, domain, queryType, clientIP, timestamp, signature)
+			return fmt.Sprintf("%s/sign_d?host=%s&query=%s&ip=%s&%s",
+				baseURL, domain, queryType, clientIP, signParams.Encode()), nil
 		} else {
-			return fmt.Sprintf("%s/sign_d?host=%s&query=%s&t=%s&s=%s",
-				baseURL, domain, queryType, timestamp, signature)
+			return fmt.Sprintf("%s/sign_d?host=%s&query=%s&%s",
+				baseURL, domain, queryType, signParams.Encode()), nil
 		}
 	} else {
 		// 非鉴权解析
 		if clientIP != "" {
 			return fmt.Sprintf("%s/d?host=%s&query=%s&ip=%s",
-				baseURL, domain, queryType, clientIP)
+				baseURL, domain, queryType, clientIP), nil
 		} else {
 			return fmt.Sprintf("%s/d?host=%s&query=%s",
-				baseURL, domain, queryType)
+				baseURL, domain, queryType), nil
 		}
 	}
 }
 
-// BuildBatchResolveURL 构建批量域名解析URL
+// BuildBatchResolveURL 构建批量域名解析URL，使用默认查询类型（IPv4和IPv6），签名失败时返回空字符串
+// （需要指定查询类型或错误信息时使用 BatchResolveURL）
 func (b *RequestBuilder) BuildBatchResolveURL(serviceIP string, domains []string, clientIP string) string {
+	resolveURL, _ := b.BatchResolveURL(serviceIP, domains, clientIP, QueryBoth)
+	return resolveURL
+}
+
+// BatchResolveURL 构建批量域名解析URL
+func (b *RequestBuilder) BatchResolveURL(serviceIP string, domains []string, clientIP string, queryType QueryType) (string, error) {
 	protocol := "http"
 	if b.config.EnableHTTPS {
 		protocol = "https"
 	}
 
-	baseURL := fmt.Sprintf("%s://%s/%s", protocol, serviceIP, b.config.AccountID)
+	accountID, signParams, err := b.sign(domains, true)
+	if err != nil {
+		return "", err
+	}
+	baseURL := fmt.Sprintf("%s://%s/%s", protocol, serviceIP, accountID)
 	hostParam := strings.Join(domains, ",")
 
-	if b.authManager != nil {
+	if signParams != nil {
 		// 鉴权解析
-		timestamp, signature := b.authManager.GenerateBatchSignature(domains)
 		if clientIP != "" {
-			return fmt.Sprintf("%s/sign_resolve?host=%s&ip=%s&t=%s&s=%s",
-				baseURL, hostParam, clientIP, timestamp, signature)
+			return fmt.Sprintf("%s/sign_resolve?host=%s&query=%s&ip=%s&%s",
+				baseURL, hostParam, queryType, clientIP, signParams.Encode()), nil
 		} else {
-			return fmt.Sprintf("%s/sign_resolve?host=%s&t=%s&s=%s",
-				baseURL, hostParam, timestamp, signature)
+			return fmt.Sprintf("%s/sign_resolve?host=%s&query=%s&%s",
+				baseURL, hostParam, queryType, signParams.Encode()), nil
 		}
 	} else {
 		// 非鉴权解析
 		if clientIP != "" {
-			return fmt.Sprintf("%s/resolve?host=%s&ip=%s",
-				baseURL, hostParam, clientIP)
+			return fmt.Sprintf("%s/resolve?host=%s&query=%s&ip=%s",
+				baseURL, hostParam, queryType, clientIP), nil
 		} else {
-			return fmt.Sprintf("%s/resolve?host=%s",
-				baseURL, hostParam)
+			return fmt.Sprintf("%s/resolve?host=%s&query=%s",
+				baseURL, hostParam, queryType), nil
 		}
 	}
 }
 
+// sign 获取当前账号和签名参数，未配置鉴权或凭据中没有密钥时签名参数为 nil
+func (b *RequestBuilder) sign(hosts []string, batch bool) (string, url.Values, error) {
+	if b.authManager == nil {
+		return b.config.AccountID, nil, nil
+	}
+
+	creds, params, err := b.authManager.Sign(hosts, batch)
+	if err != nil {
+		return "", nil, NewHTTPDNSError("sign_request", strings.Join(hosts, ","), err)
+	}
+	if creds.AccountID == "" {
+		creds.AccountID = b.config.AccountID
+	}
+	return creds.AccountID, params, nil
+}
+
 // BuildServiceIPURL 构建服务IP获取URL
 func (b *RequestBuilder) BuildServiceIPURL(bootstrapIP string) string {
 	protocol := "http"
@@ -142,65 +192,207 @@ func (b *RequestBuilder) BuildServiceIPURL(bootstrapIP string) string {
 		protocol = "https"
 	}
 
-	return fmt.Sprintf("%s://%s/%s/ss", protocol, bootstrapIP, b.config.AccountID)
+	return fmt.Sprintf("%s://%s/%s/ss", protocol, bootstrapIP, b.accountID())
+}
+
+// accountID 返回当前使用的账号（配置了凭据提供者时以其为准）
+func (b *RequestBuilder) accountID() string {
+	if b.authManager != nil {
+		if creds, err := b.authManager.Credentials(); err == nil && creds.AccountID != "" {
+			return creds.AccountID
+		}
+	}
+	return b.config.AccountID
 }
 
-// DoRequest 执行HTTP请求
+// DoRequest 执行HTTP请求，ctx 没有截止时间时以 Config.Timeout 为超时
 func (c *HTTPDNSClient) DoRequest(ctx context.Context, url string) (*http.Response, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	return c.doRequest(ctx, url, c.attemptTimeout(ctx, 1))
+}
+
+// doRequest 以 timeout 为超时执行单次HTTP请求，超时持续到响应体关闭
+func (c *HTTPDNSClient) doRequest(ctx context.Context, url string, timeout time.Duration) (*http.Response, error) {
+	reqCtx, cancel := context.WithTimeout(ctx, timeout)
+	req, err := http.NewRequestWithContext(reqCtx, "GET", url, nil)
 	if err != nil {
+		cancel()
 		return nil, NewHTTPDNSError("create_request", "", err)
 	}
 
+	serviceIP := req.URL.Host
+	sentAt := time.Now()
 	resp, err := c.client.Do(req)
 	if err != nil {
+		cancel()
+		// 调用方取消（如对冲请求落败）不计入服务IP失败，但释放可能占用的半开探测；单次尝试超时计入失败
+		if ctx.Err() == nil {
+			c.serviceIPManager.RecordFailure(serviceIP)
+		} else {
+			c.serviceIPManager.ReleaseProbe(serviceIP)
+		}
 		return nil, NewHTTPDNSError("http_request", "", err)
 	}
+	receivedAt := time.Now()
+	c.clock.observe(resp, sentAt, receivedAt)
+
+	// 5xx 说明服务IP异常，其他状态码说明服务IP可用
+	if resp.StatusCode >= http.StatusInternalServerError {
+		c.serviceIPManager.RecordFailure(serviceIP)
+	} else {
+		c.serviceIPManager.RecordSuccess(serviceIP, receivedAt.Sub(sentAt))
+	}
 
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
 	return resp, nil
 }
 
+// minAttemptTimeout 均分剩余时间时单次尝试的最小超时
+const minAttemptTimeout = 100 * time.Millisecond
+
+// attemptTimeout 计算单次尝试的超时：ctx 有截止时间时按剩余尝试次数均分剩余时间，
+// 并以 Config.AttemptTimeout 为上限；否则使用 Config.AttemptTimeout，未设置时使用 Config.Timeout
+func (c *HTTPDNSClient) attemptTimeout(ctx context.Context, attemptsLeft int) time.Duration {
+	timeout := c.config.AttemptTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		if attemptsLeft < 1 {
+			attemptsLeft = 1
+		}
+		share := time.Until(deadline) / time.Duration(attemptsLeft)
+		if share < minAttemptTimeout {
+			share = minAttemptTimeout
+		}
+		if timeout <= 0 || share < timeout {
+			timeout = share
+		}
+		return timeout
+	}
+	if timeout <= 0 {
+		timeout = c.config.Timeout
+	}
+	return timeout
+}
+
+// cancelOnClose 关闭响应体时释放单次请求的上下文
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
 // AuthManager 鉴权管理器
 type AuthManager struct {
-	secretKey  string
-	expireTime time.Duration
+	secretKey   string             // 静态密钥（未配置凭据提供者时使用）
+	credentials CredentialProvider // 凭据提供者，为 nil 时使用 secretKey
+	signer      Signer
+	expireTime  time.Duration
+	clock       *clockSkew // 时钟偏差估计，为 nil 时使用本地时间
 }
 
-// NewAuthManager 创建鉴权管理器
+// NewAuthManager 创建使用固定密钥和 MD5 签名的鉴权管理器
 func NewAuthManager(secretKey string, expireTime time.Duration) *AuthManager {
 	return &AuthManager{
 		secretKey:  secretKey,
+		signer:     MD5Signer{},
 		expireTime: expireTime,
 	}
 }
 
-// GenerateSignature 生成单域名解析签名
+// NewAuthManagerWithProvider 创建使用凭据提供者和自定义签名器的鉴权管理器，signer 为 nil 时使用 MD5Signer
+func NewAuthManagerWithProvider(credentials CredentialProvider, signer Signer, expireTime time.Duration) *AuthManager {
+	if signer == nil {
+		signer = MD5Signer{}
+	}
+	return &AuthManager{
+		credentials: credentials,
+		signer:      signer,
+		expireTime:  expireTime,
+	}
+}
+
+// now 返回按时钟偏差校正后的当前时间
+func (a *AuthManager) now() time.Time {
+	return a.clock.Now()
+}
+
+// Credentials 返回当前凭据
+func (a *AuthManager) Credentials() (Credentials, error) {
+	if a.credentials == nil {
+		return Credentials{SecretKey: a.secretKey}, nil
+	}
+	return a.credentials.Credentials()
+}
+
+// Sign 使用当前凭据对请求签名，凭据中没有密钥时返回 nil 参数（使用非鉴权解析）
+func (a *AuthManager) Sign(hosts []string, batch bool) (Credentials, url.Values, error) {
+	creds, err := a.Credentials()
+	if err != nil {
+		return creds, nil, err
+	}
+	if creds.SecretKey == "" {
+		return creds, nil, nil
+	}
+
+	// 使用当前时间加上过期时间作为时间戳，确保请求在有效期内
+	params, err := a.signer.Sign(&SignRequest{
+		Hosts:       hosts,
+		Batch:       batch,
+		ExpireAt:    a.now().Add(a.expireTime),
+		Credentials: creds,
+	})
+	if err != nil {
+		return creds, nil, err
+	}
+	return creds, params, nil
+}
+
+// GenerateSignature 生成单域名解析的 MD5 签名
 func (a *AuthManager) GenerateSignature(host string) (timestamp, signature string) {
 	// 使用当前时间加上过期时间作为时间戳，确保请求在有效期内
-	expireAt := time.Now().Add(a.expireTime)
+	expireAt := a.now().Add(a.expireTime)
 	timestamp = strconv.FormatInt(expireAt.Unix(), 10)
-	signature = generateSignature(a.secretKey, host, timestamp)
+	creds, _ := a.Credentials()
+	signature = generateSignature(creds.SecretKey, host, timestamp)
 	return
 }
 
-// GenerateBatchSignature 生成批量解析签名
+// GenerateBatchSignature 生成批量解析的 MD5 签名
 func (a *AuthManager) GenerateBatchSignature(hosts []string) (timestamp, signature string) {
 	// 使用当前时间加上过期时间作为时间戳，确保请求在有效期内
-	expireAt := time.Now().Add(a.expireTime)
+	expireAt := a.now().Add(a.expireTime)
 	timestamp = strconv.FormatInt(expireAt.Unix(), 10)
-	signature = generateBatchSignature(a.secretKey, hosts, timestamp)
+	creds, _ := a.Credentials()
+	signature = generateBatchSignature(creds.SecretKey, hosts, timestamp)
 	return
 }
 
-// FetchServiceIPs 获取服务IP列表
+// FetchServiceIPs 获取服务IP列表，按 Config.ServiceIPRetryPolicy 重试
 func (c *HTTPDNSClient) FetchServiceIPs(ctx context.Context) error {
-	ips, err := c.bootstrapManager.FetchServiceIPs(ctx, c.client, c.config.AccountID, c.config.EnableHTTPS)
-	if err != nil {
-		return NewHTTPDNSError("fetch_service_ips", "", err)
+	policy := c.config.ServiceIPRetryPolicy
+	if policy == nil {
+		policy = NewExponentialBackoff(0)
+	}
+	accountID := NewRequestBuilder(c.config, c.authManager).accountID()
+
+	for attempt := 1; ; attempt++ {
+		fetchCtx, cancel := context.WithTimeout(ctx, c.attemptTimeout(ctx, policy.MaxAttempts()-attempt+1))
+		ips, err := c.bootstrapManager.FetchServiceIPs(fetchCtx, c.client, accountID, c.config.EnableHTTPS)
+		cancel()
+		if err == nil {
+			c.serviceIPManager.UpdateServiceIPs(ips)
+			return nil
+		}
+		if attempt >= policy.MaxAttempts() || !policy.Retryable(err) || ctx.Err() != nil {
+			return NewHTTPDNSError("fetch_service_ips", "", err)
+		}
+		if sleepErr := sleepContext(ctx, policy.Backoff(attempt)); sleepErr != nil {
+			return NewHTTPDNSError("fetch_service_ips", "", err)
+		}
 	}
-
-	c.serviceIPManager.UpdateServiceIPs(ips)
-	return nil
 }
 
 // GetAvailableServiceIP 获取可用的服务IP
@@ -223,60 +415,110 @@ func (c *HTTPDNSClient) MarkServiceIPFailed(ip string) {
 	c.serviceIPManager.MarkIPFailed(ip)
 }
 
-// DoRequestWithRetry 执行HTTP请求并处理故障转移
+// ServiceIPStats 返回各服务IP的延迟、错误率评分和熔断状态
+func (c *HTTPDNSClient) ServiceIPStats() []ServiceIPStats {
+	return c.serviceIPManager.Stats()
+}
+
+// DoRequestWithRetry 按 Config.RetryPolicy 执行HTTP请求并处理故障转移，失败时返回包含每次尝试详情的 RetryError
 func (c *HTTPDNSClient) DoRequestWithRetry(ctx context.Context, buildURL func() (string, error)) (*http.Response, error) {
-	var lastErr error
-	maxAttempts := c.config.MaxRetries + 1 // 至少执行一次请求
+	return c.DoRequestWithPolicy(ctx, c.config.RetryPolicy, buildURL)
+}
+
+// DoRequestWithPolicy 按指定重试策略执行HTTP请求并处理故障转移，policy 为 nil 时按 Config.MaxRetries 指数退避
+func (c *HTTPDNSClient) DoRequestWithPolicy(ctx context.Context, policy RetryPolicy, buildURL func() (string, error)) (*http.Response, error) {
+	if policy == nil {
+		policy = NewExponentialBackoff(c.config.MaxRetries)
+	}
+	retryErr := &RetryError{}
+	maxAttempts := policy.MaxAttempts()
+	skewRetried := false
+
+	for attempt := 1; ; attempt++ {
+		startTime := time.Now()
+		info := RequestAttempt{}
 
-	for attempt := 0; attempt < maxAttempts; attempt++ {
 		// 每次重试都获取新的 URL
 		url, err := buildURL()
 		if err != nil {
-			lastErr = err
-			// 如果构建 URL 失败，等待后继续重试
-			if attempt < maxAttempts-1 {
-				select {
-				case <-ctx.Done():
-					return nil, ctx.Err()
-				case <-time.After(time.Duration(attempt+1) * time.Second):
+			info.Err = err
+		} else {
+			skewBefore := c.clock.Offset()
+			info.Timeout = c.attemptTimeout(ctx, maxAttempts-attempt+1)
+			result := c.doHedgedRequest(ctx, url, info.Timeout)
+			resp, err := result.resp, result.err
+			info.ServiceIP, info.Hedged = result.serviceIP, result.hedged
+			info.Duration = time.Since(startTime)
+			if resp != nil {
+				info.StatusCode = resp.StatusCode
+			}
+			if err == nil && resp.StatusCode == http.StatusOK {
+				c.recordAttempt(retryErr, info)
+				return resp, nil
+			}
+
+			if err != nil && errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+				// 单次尝试超时而整体时间未用完，可以换服务IP重试
+				info.Err = NewHTTPDNSError("http_request", "", fmt.Errorf("%w: attempt timed out after %v", ErrNetworkTimeout, info.Timeout))
+			} else if err != nil {
+				info.Err = err
+			} else {
+				serverErr := newServerError(resp)
+				resp.Body.Close()
+				if c.isClockSkewRejection(serverErr, skewBefore) {
+					info.Err = NewHTTPDNSError("signature_rejected", "",
+						fmt.Errorf("%w (estimated skew %v): %w", ErrClockSkew, c.clock.Offset(), serverErr))
+				} else {
+					info.Err = NewHTTPDNSError("http_status", "", serverErr)
 				}
 			}
-			continue
 		}
+		info.Duration = time.Since(startTime)
+		c.recordAttempt(retryErr, info)
 
-		resp, err := c.DoRequest(ctx, url)
-		if err == nil && resp.StatusCode == http.StatusOK {
-			return resp, nil
+		// 偏差估计已根据本次响应更新，立即重新签名重试一次，不计入重试次数
+		if errors.Is(info.Err, ErrClockSkew) && !skewRetried {
+			skewRetried = true
+			attempt--
+			continue
 		}
-
-		if resp != nil {
-			resp.Body.Close()
+		if !policy.Retryable(info.Err) {
+			return nil, NewHTTPDNSError("request_failed", "", retryErr)
 		}
-
-		if err != nil {
-			lastErr = err
-		} else {
-			lastErr = NewHTTPDNSError("http_status", "",
-				fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status))
+		if attempt >= maxAttempts {
+			return nil, NewHTTPDNSError("request_retry_failed", "", retryErr)
 		}
 
-		// 如果还有重试机会，进行重试准备
-		if attempt < maxAttempts-1 {
-			// 从URL中提取服务IP并标记为失败
-			if serviceIP := extractServiceIPFromURL(url); serviceIP != "" {
-				c.MarkServiceIPFailed(serviceIP)
-			}
+		// 服务IP的失败已在 doRequest 中计入健康评分，等待后重试
+		if err := sleepContext(ctx, policy.Backoff(attempt)); err != nil {
+			retryErr.Cause = err
+			return nil, NewHTTPDNSError("request_retry_failed", "", retryErr)
+		}
+	}
+}
 
-			// 等待一段时间后重试
-			select {
-			case <-ctx.Done():
-				return nil, ctx.Err()
-			case <-time.After(time.Duration(attempt+1) * time.Second): // 指数退避
-			}
+// recordAttempt 记录一次请求尝试：失败的尝试加入 RetryError 并写日志，所有尝试都通知 OnRequestAttempt 钩子
+func (c *HTTPDNSClient) recordAttempt(retryErr *RetryError, attempt RequestAttempt) {
+	attempt.Attempt = len(retryErr.Attempts) + 1
+	if attempt.Err != nil {
+		retryErr.Attempts = append(retryErr.Attempts, attempt)
+		if c.config.Logger != nil {
+			c.config.Logger.Printf("Request %s", attempt)
 		}
 	}
+	if c.config.OnRequestAttempt != nil {
+		c.config.OnRequestAttempt(attempt)
+	}
+}
 
-	return nil, NewHTTPDNSError("request_retry_failed", "", lastErr)
+// isClockSkewRejection 判断服务端拒绝是否由时钟偏差导致：
+// 服务端返回签名过期，或 403 响应揭示的偏差变化已超出签名有效期
+func (c *HTTPDNSClient) isClockSkewRejection(serverErr *ServerError, skewBefore time.Duration) bool {
+	if errors.Is(serverErr, ErrSignatureExpired) {
+		return true
+	}
+	return serverErr.StatusCode == http.StatusForbidden &&
+		absDuration(c.clock.Offset()-skewBefore) >= c.config.SignatureExpireTime
 }
 
 // extractServiceIPFromURL 从URL中提取服务IP
@@ -2,6 +2,7 @@ package httpdns
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -58,8 +59,9 @@ func TestNewHTTPClient(t *testing.T) {
 				t.Fatal("newHTTPClient() returned nil")
 			}
 
-			if client.Timeout != tt.config.Timeout {
-				t.Errorf("newHTTPClient() timeout = %v, want %v", client.Timeout, tt.config.Timeout)
+			// 超时由每次请求的上下文控制，客户端不设置整体超时
+			if client.Timeout != 0 {
+				t.Errorf("newHTTPClient() timeout = %v, want 0", client.Timeout)
 			}
 
 			transport, ok := client.Transport.(*http.Transport)
@@ -152,6 +154,7 @@ func TestRequestBuilder_BuildBatchResolveURL(t *testing.T) {
 		serviceIP   string
 		domains     []string
 		clientIP    string
+		queryType   QueryType
 		wantContain []string
 	}{
 		{
@@ -160,7 +163,8 @@ func TestRequestBuilder_BuildBatchResolveURL(t *testing.T) {
 			serviceIP:   "203.107.1.1",
 			domains:     []string{"example.com", "test.com"},
 			clientIP:    "1.2.3.4",
-			wantContain: []string{"http://203.107.1.1/test123/resolve", "host=example.com,test.com", "ip=1.2.3.4"},
+			queryType:   QueryBoth,
+			wantContain: []string{"http://203.107.1.1/test123/resolve", "host=example.com,test.com", "query=4,6", "ip=1.2.3.4"},
 		},
 		{
 			name:        "auth batch resolve",
@@ -168,22 +172,32 @@ func TestRequestBuilder_BuildBatchResolveURL(t *testing.T) {
 			serviceIP:   "203.107.1.1",
 			domains:     []string{"example.com", "test.com"},
 			clientIP:    "",
-			wantContain: []string{"http://203.107.1.1/test123/sign_resolve", "host=example.com,test.com", "t=", "s="},
+			queryType:   QueryIPv4,
+			wantContain: []string{"http://203.107.1.1/test123/sign_resolve", "host=example.com,test.com", "query=4", "t=", "s="},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			builder := NewRequestBuilder(config, tt.authManager)
-			url := builder.BuildBatchResolveURL(tt.serviceIP, tt.domains, tt.clientIP)
+			url, err := builder.BatchResolveURL(tt.serviceIP, tt.domains, tt.clientIP, tt.queryType)
+			if err != nil {
+				t.Fatalf("BatchResolveURL() error = %v", err)
+			}
 
 			for _, contain := range tt.wantContain {
 				if !strings.Contains(url, contain) {
-					t.Errorf("BuildBatchResolveURL() = %v, should contain %v", url, contain)
+					t.Errorf("BatchResolveURL() = %v, should contain %v", url, contain)
 				}
 			}
 		})
 	}
+
+	// BuildBatchResolveURL 保持原签名，使用默认查询类型
+	url := NewRequestBuilder(config, nil).BuildBatchResolveURL("203.107.1.1", []string{"example.com"}, "")
+	if !strings.Contains(url, "/resolve?host=example.com&query=4,6") {
+		t.Errorf("BuildBatchResolveURL() = %v, want default query type 4,6", url)
+	}
 }
 
 func TestRequestBuilder_BuildServiceIPURL(t *testing.T) {
@@ -388,3 +402,133 @@ func TestExtractServiceIPFromURL(t *testing.T) {
 		})
 	}
 }
+
+func TestHTTPDNSClient_AttemptTimeout(t *testing.T) {
+	config := DefaultConfig()
+	config.Timeout = 5 * time.Second
+	client := NewHTTPDNSClient(config)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	// 按剩余尝试次数均分剩余时间
+	if got := client.attemptTimeout(ctx, 3); got > time.Second || got < 900*time.Millisecond {
+		t.Errorf("attemptTimeout(3 attempts left) = %v, want about 1s", got)
+	}
+	// 最后一次尝试使用全部剩余时间，不受 Config.Timeout 限制
+	longCtx, longCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer longCancel()
+	if got := client.attemptTimeout(longCtx, 1); got <= config.Timeout {
+		t.Errorf("attemptTimeout(last attempt) = %v, want remaining budget above %v", got, config.Timeout)
+	}
+	// 没有截止时间时使用 Config.Timeout
+	if got := client.attemptTimeout(context.Background(), 3); got != config.Timeout {
+		t.Errorf("attemptTimeout(no deadline) = %v, want %v", got, config.Timeout)
+	}
+	// 剩余时间不足时使用最小超时
+	shortCtx, shortCancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer shortCancel()
+	if got := client.attemptTimeout(shortCtx, 3); got != minAttemptTimeout {
+		t.Errorf("attemptTimeout(short deadline) = %v, want %v", got, minAttemptTimeout)
+	}
+
+	// AttemptTimeout 作为上限
+	config.AttemptTimeout = 500 * time.Millisecond
+	if got := client.attemptTimeout(ctx, 3); got != config.AttemptTimeout {
+		t.Errorf("attemptTimeout(with AttemptTimeout) = %v, want %v", got, config.AttemptTimeout)
+	}
+	if got := client.attemptTimeout(context.Background(), 1); got != config.AttemptTimeout {
+		t.Errorf("attemptTimeout(no deadline, AttemptTimeout) = %v, want %v", got, config.AttemptTimeout)
+	}
+}
+
+func TestResolver_AttemptTimeoutFailover(t *testing.T) {
+	newServer := func(delay time.Duration) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-time.After(delay):
+			case <-r.Context().Done():
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(HTTPDNSResponse{Host: r.URL.Query().Get("host"), IPs: []string{"1.2.3.4"}, TTL: 60})
+		}))
+	}
+	slow := newServer(5 * time.Second)
+	defer slow.Close()
+	fast := newServer(300 * time.Millisecond)
+	defer fast.Close()
+
+	config := DefaultConfig()
+	config.AccountID = "test123"
+	config.Timeout = 100 * time.Millisecond
+	config.EnableMemoryCache = false
+	config.RetryPolicy = &ExponentialBackoff{MaxRetries: 1, BaseDelay: time.Millisecond}
+	resolver := NewResolver(config)
+	resolver.httpClient.serviceIPManager.UpdateServiceIPs([]string{slow.URL[7:], fast.URL[7:]})
+
+	// 慢服务IP只占用一半时间，剩余时间足够换到较快的服务IP；整体超时大于 Config.Timeout 时不会被截断
+	start := time.Now()
+	result, err := resolver.ResolveSingle(context.Background(), "example.com", WithTimeout(2*time.Second))
+	if err != nil {
+		t.Fatalf("ResolveSingle() error = %v", err)
+	}
+	if len(result.IPv4) != 1 {
+		t.Errorf("ResolveSingle() IPv4 = %v, want 1 address", result.IPv4)
+	}
+	if elapsed := time.Since(start); elapsed > 1800*time.Millisecond {
+		t.Errorf("ResolveSingle() took %v, want failover within the 2s budget", elapsed)
+	}
+}
+
+func TestResolver_ServiceIPHealth(t *testing.T) {
+	good := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(HTTPDNSResponse{Host: r.URL.Query().Get("host"), IPs: []string{"1.2.3.4"}, TTL: 60})
+	}))
+	defer good.Close()
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer bad.Close()
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	config := DefaultConfig()
+	config.AccountID = "test123"
+	config.EnableMemoryCache = false
+	config.RetryPolicy = &ExponentialBackoff{MaxRetries: 2, BaseDelay: time.Millisecond}
+	resolver := NewResolver(config)
+	resolver.httpClient.serviceIPManager.UpdateServiceIPs([]string{down.URL[7:], bad.URL[7:], good.URL[7:]})
+
+	// 首次解析依次尝试未请求过的IP，失败的IP评分变差，之后只使用可用的IP
+	for i := 0; i < 3; i++ {
+		if _, err := resolver.ResolveSingle(context.Background(), "example.com"); err != nil {
+			t.Fatalf("ResolveSingle() error = %v", err)
+		}
+	}
+
+	stats := resolver.httpClient.ServiceIPStats()
+	if len(stats) != 3 {
+		t.Fatalf("ServiceIPStats() = %+v, want 3 entries", stats)
+	}
+	for i, want := range []struct {
+		failures, successes int64
+	}{{1, 0}, {1, 0}, {0, 3}} {
+		if stats[i].Failures != want.failures || stats[i].Successes != want.successes {
+			t.Errorf("ServiceIPStats()[%d] = %+v, want %d failures and %d successes", i, stats[i], want.failures, want.successes)
+		}
+	}
+	if stats[2].Latency <= 0 || stats[2].Score >= stats[1].Score {
+		t.Errorf("ServiceIPStats() good = %+v, bad = %+v, want good to score better", stats[2], stats[1])
+	}
+
+	// 连续失败达到阈值后熔断
+	resolver.httpClient.serviceIPManager.UpdateServiceIPs([]string{bad.URL[7:]})
+	if _, err := resolver.ResolveSingle(context.Background(), "example.com"); err == nil {
+		t.Fatal("ResolveSingle() expected error")
+	}
+	if stats := resolver.httpClient.ServiceIPStats(); stats[0].State != BreakerOpen {
+		t.Errorf("ServiceIPStats() state = %v, want open", stats[0].State)
+	}
+}
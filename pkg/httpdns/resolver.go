@@ -3,6 +3,7 @@ package httpdns
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net"
 	"sync"
 	"time"
@@ -18,20 +19,31 @@ type Resolver struct {
 	// 异步更新控制（防止同一域名重复刷新）
 	updateMu sync.Mutex
 	updating map[string]bool
+
+	// 异常结果保护（记录各域名连续异常结果）
+	anomalyMu sync.Mutex
+	anomalies map[string]*answerAnomaly
 }
 
 // NewResolver 创建新的解析器
 func NewResolver(config *Config) *Resolver {
 	httpClient := NewHTTPDNSClient(config)
 
-	// 如果配置了SecretKey，设置鉴权管理器
-	if config.SecretKey != "" {
+	// 如果配置了SecretKey或凭据提供者，设置鉴权管理器
+	if config.CredentialProvider != nil {
+		httpClient.SetAuthManager(NewAuthManagerWithProvider(config.CredentialProvider, config.Signer, config.SignatureExpireTime))
+	} else if config.SecretKey != "" {
 		authManager := NewAuthManager(config.SecretKey, config.SignatureExpireTime)
+		if config.Signer != nil {
+			authManager.signer = config.Signer
+		}
 		httpClient.SetAuthManager(authManager)
 	}
 
 	// 创建缓存管理器
+	metrics := NewMetricsCollector(config.EnableMetrics)
 	cacheManager := NewCacheManager(config)
+	cacheManager.metrics = metrics
 
 	// 如果启用持久化缓存，从磁盘加载缓存
 	if config.EnablePersistentCache {
@@ -43,7 +55,7 @@ func NewResolver(config *Config) *Resolver {
 	return &Resolver{
 		httpClient:   httpClient,
 		config:       config,
-		metrics:      NewMetricsCollector(config.EnableMetrics),
+		metrics:      metrics,
 		cacheManager: cacheManager,
 		updating:     make(map[string]bool),
 	}
@@ -53,17 +65,11 @@ func NewResolver(config *Config) *Resolver {
 func (r *Resolver) ResolveSingle(ctx context.Context, domain string, opts ...ResolveOption) (*ResolveResult, error) {
 	startTime := time.Now()
 	// 应用选项
-	options := &ResolveOptions{
-		QueryType: QueryBoth, // 默认查询IPv4和IPv6
-		Timeout:   r.config.Timeout,
-	}
-
-	for _, opt := range opts {
-		opt(options)
-	}
+	options := r.newResolveOptions(opts)
 
 	// 查询缓存
-	if entry, hit, needAsyncUpdate := r.cacheManager.Get(domain); hit {
+	staleWhileRevalidate, staleIfError := r.staleWindows(options)
+	if entry, hit, needAsyncUpdate := r.cacheManager.Lookup(domain, staleWhileRevalidate); hit {
 		if r.config.Logger != nil {
 			r.config.Logger.Printf("Cache hit for domain: %s, expired: %v", domain, needAsyncUpdate)
 		}
@@ -71,7 +77,7 @@ func (r *Resolver) ResolveSingle(ctx context.Context, domain string, opts ...Res
 		// 如果需要异步更新，启动后台更新
 		if needAsyncUpdate {
 			r.tryAsyncUpdate(domain, func() {
-				r.asyncUpdate(ctx, domain, options.ClientIP, options.QueryType)
+				r.asyncUpdate(domain, options)
 			})
 		}
 		
@@ -85,6 +91,20 @@ func (r *Resolver) ResolveSingle(ctx context.Context, domain string, opts ...Res
 		return result, nil
 	}
 
+	// 查询负缓存
+	if cause := r.cacheManager.GetNegative(domain); cause != nil {
+		recordNegativeCacheHit(r.metrics)
+		if stale := r.staleIfError(domain, options.ClientIP, staleIfError, cause); stale != nil {
+			latency := time.Since(startTime)
+			r.metrics.RecordResolve(true, latency, stale.Source)
+			return stale, nil
+		}
+
+		latency := time.Since(startTime)
+		r.metrics.RecordResolve(false, latency, SourceHTTPDNS)
+		return nil, NewHTTPDNSError("negative_cache", domain, cause)
+	}
+
 	// 创建带超时的上下文
 	if options.Timeout > 0 {
 		var cancel context.CancelFunc
@@ -92,65 +112,23 @@ func (r *Resolver) ResolveSingle(ctx context.Context, domain string, opts ...Res
 		defer cancel()
 	}
 
-	// 确保有可用的服务IP
-	if err := r.httpClient.UpdateServiceIPsIfNeeded(ctx); err != nil {
-		return nil, NewHTTPDNSError("resolve_single", domain, err)
-	}
-
-	// 执行HTTP请求（每次重试都会获取新的服务IP并构建URL）
-	builder := NewRequestBuilder(r.config, r.httpClient.authManager)
-	resp, err := r.httpClient.DoRequestWithRetry(ctx, func() (string, error) {
-		serviceIP, err := r.httpClient.GetAvailableServiceIP()
-		if err != nil {
-			return "", err
-		}
-		return builder.BuildSingleResolveURL(serviceIP, domain, options.ClientIP, options.QueryType), nil
-	})
+	result, err := r.fetchSingle(ctx, domain, options)
 	if err != nil {
 		// 记录错误指标
 		r.metrics.RecordError(err)
-		latency := time.Since(startTime)
-		r.metrics.RecordResolve(false, latency, SourceHTTPDNS)
-		return nil, NewHTTPDNSError("resolve_single", domain, err)
-	}
-	defer resp.Body.Close()
 
-	// 解析响应
-	var dnsResp HTTPDNSResponse
-	if err := json.NewDecoder(resp.Body).Decode(&dnsResp); err != nil {
-		return nil, NewHTTPDNSError("resolve_single", domain, err)
-	}
-
-	// 转换为ResolveResult
-	result := &ResolveResult{
-		Domain:    domain,
-		ClientIP:  options.ClientIP,
-		Source:    SourceHTTPDNS,
-		Timestamp: time.Now(),
-	}
-
-	// 解析IPv4地址
-	for _, ipStr := range dnsResp.IPs {
-		if ip := net.ParseIP(ipStr); ip != nil {
-			result.IPv4 = append(result.IPv4, ip)
+		// 网络失败时尝试返回窗口内的过期缓存
+		if stale := r.staleIfError(domain, options.ClientIP, staleIfError, err); stale != nil {
+			latency := time.Since(startTime)
+			r.metrics.RecordResolve(true, latency, stale.Source)
+			return stale, nil
 		}
-	}
 
-	// 解析IPv6地址
-	for _, ipStr := range dnsResp.IPsV6 {
-		if ip := net.ParseIP(ipStr); ip != nil {
-			result.IPv6 = append(result.IPv6, ip)
-		}
-	}
-
-	// 设置TTL
-	if dnsResp.TTL > 0 {
-		result.TTL = time.Duration(dnsResp.TTL) * time.Second
+		latency := time.Since(startTime)
+		r.metrics.RecordResolve(false, latency, SourceHTTPDNS)
+		return nil, NewHTTPDNSError("resolve_single", domain, err)
 	}
 
-	// 更新缓存
-	r.updateCache(domain, result, dnsResp.TTL)
-
 	// 记录指标
 	latency := time.Since(startTime)
 	r.metrics.RecordResolve(true, latency, result.Source)
@@ -159,102 +137,202 @@ func (r *Resolver) ResolveSingle(ctx context.Context, domain string, opts ...Res
 }
 
 // ResolveBatch 批量解析域名
+// 域名会被规范化并去重，超过单次请求上限的部分会自动拆分为多个批量请求并发执行。
+// 返回结果与输入一一对应（重复域名共享同一结果），单个域名的失败记录在 ResolveResult.Error 中；
+// 仅当输入为空或所有域名均解析失败时返回错误
 func (r *Resolver) ResolveBatch(ctx context.Context, domains []string, opts ...ResolveOption) ([]*ResolveResult, error) {
+	domainResults, err := r.resolveBatch(ctx, domains, opts)
+	if domainResults == nil {
+		return nil, err
+	}
+
+	results := make([]*ResolveResult, 0, len(domains))
+	for _, domain := range domains {
+		results = append(results, domainResults[normalizeDomain(domain)])
+	}
+
+	return results, err
+}
+
+// ResolveBatchMap 批量解析域名，返回以规范化域名为键的结果
+// 错误语义与 ResolveBatch 相同
+func (r *Resolver) ResolveBatchMap(ctx context.Context, domains []string, opts ...ResolveOption) (map[string]*ResolveResult, error) {
+	return r.resolveBatch(ctx, domains, opts)
+}
+
+// resolveBatch 批量解析的核心实现，返回规范化域名到结果的映射（每个输入域名都有对应结果）
+func (r *Resolver) resolveBatch(ctx context.Context, domains []string, opts []ResolveOption) (map[string]*ResolveResult, error) {
 	startTime := time.Now()
-	
+
 	if len(domains) == 0 {
 		return nil, NewHTTPDNSError("resolve_batch", "", ErrInvalidDomain)
 	}
 
-	// 检查域名数量限制，最多支持5个域名
-	if len(domains) > 5 {
-		return nil, NewHTTPDNSError("resolve_batch", "", ErrTooManyDomains)
-	}
-
 	// 应用选项
-	options := &ResolveOptions{
-		QueryType: QueryBoth,
-		Timeout:   r.config.Timeout,
-	}
+	options := r.newResolveOptions(opts)
 
-	for _, opt := range opts {
-		opt(options)
-	}
+	// 规范化并去重
+	uniqueDomains := dedupeDomains(domains)
+	results := make(map[string]*ResolveResult, len(uniqueDomains))
+	staleWhileRevalidate, staleIfError := r.staleWindows(options)
 
-	// 查询缓存，分离命中和未命中的域名
-	cachedResults := make([]*ResolveResult, 0)
+	// 校验域名并查询缓存，分离命中和未命中的域名
 	uncachedDomains := make([]string, 0)
-	
-	for _, domain := range domains {
-		if entry, hit, needAsyncUpdate := r.cacheManager.Get(domain); hit {
+
+	for _, domain := range uniqueDomains {
+		if err := ValidateDomain(domain); err != nil {
+			results[domain] = newErrorResult(domain, options.ClientIP, NewHTTPDNSError("validate_domain", domain, err))
+			continue
+		}
+
+		if entry, hit, needAsyncUpdate := r.cacheManager.Lookup(domain, staleWhileRevalidate); hit {
 			if r.config.Logger != nil {
 				r.config.Logger.Printf("Cache hit for domain: %s, expired: %v", domain, needAsyncUpdate)
 			}
-			
+
 			result := entry.ToResolveResult(domain)
 			result.ClientIP = options.ClientIP
-			cachedResults = append(cachedResults, result)
-			
+			results[domain] = result
+
 			// 如果需要异步更新，启动后台更新
 			if needAsyncUpdate {
+				domain := domain
 				r.tryAsyncUpdate(domain, func() {
-					r.asyncUpdate(ctx, domain, options.ClientIP, options.QueryType)
+					r.asyncUpdate(domain, options)
 				})
 			}
+		} else if cause := r.cacheManager.GetNegative(domain); cause != nil {
+			recordNegativeCacheHit(r.metrics)
+			results[domain] = newErrorResult(domain, options.ClientIP, NewHTTPDNSError("negative_cache", domain, cause))
 		} else {
 			uncachedDomains = append(uncachedDomains, domain)
 		}
 	}
 
-	// 如果所有域名都命中缓存，直接返回
-	if len(uncachedDomains) == 0 {
-		latency := time.Since(startTime)
-		r.metrics.RecordResolve(true, latency, SourceHTTPDNS)
-		return cachedResults, nil
+	if len(uncachedDomains) > 0 {
+		// 创建带超时的上下文
+		if options.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, options.Timeout)
+			defer cancel()
+		}
+
+		// 确保有可用的服务IP，失败时所有未命中的域名均记为失败
+		if err := r.httpClient.UpdateServiceIPsIfNeeded(ctx); err != nil {
+			for _, domain := range uncachedDomains {
+				results[domain] = newErrorResult(domain, options.ClientIP, NewHTTPDNSError("resolve_batch", domain, err))
+			}
+		} else {
+			// 按单次请求上限拆分后并发解析
+			chunks := splitDomains(uncachedDomains, r.config.MaxBatchDomains)
+			for domain, result := range r.resolveBatchChunks(ctx, chunks, options) {
+				results[domain] = result
+			}
+		}
 	}
 
-	// 创建带超时的上下文
-	if options.Timeout > 0 {
-		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(ctx, options.Timeout)
-		defer cancel()
+	// 统计失败域名，网络失败时尝试返回窗口内的过期缓存
+	var firstErr error
+	failed := 0
+	for _, domain := range uniqueDomains {
+		if err := results[domain].Error; err != nil {
+			r.metrics.RecordError(err)
+			if stale := r.staleIfError(domain, options.ClientIP, staleIfError, err); stale != nil {
+				results[domain] = stale
+				continue
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+			failed++
+		}
 	}
 
-	// 确保有可用的服务IP
-	if err := r.httpClient.UpdateServiceIPsIfNeeded(ctx); err != nil {
-		// 记录错误指标
-		r.metrics.RecordError(err)
-		latency := time.Since(startTime)
-		r.metrics.RecordResolve(false, latency, SourceHTTPDNS)
-		return nil, NewHTTPDNSError("resolve_batch", "", err)
+	latency := time.Since(startTime)
+	r.metrics.RecordResolve(failed == 0, latency, SourceHTTPDNS)
+
+	if failed == len(uniqueDomains) {
+		return results, NewHTTPDNSError("resolve_batch", "", firstErr)
+	}
+
+	return results, nil
+}
+
+// resolveBatchChunks 并发执行多个批量请求，并发数受 BatchConcurrency 限制
+// 返回每个域名的结果，分片失败时该分片内的域名结果携带对应错误
+func (r *Resolver) resolveBatchChunks(ctx context.Context, chunks [][]string, options *ResolveOptions) map[string]*ResolveResult {
+	concurrency := r.config.BatchConcurrency
+	if concurrency <= 0 {
+		concurrency = DefaultBatchConcurrency
+	}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	results := make(map[string]*ResolveResult)
+	sem := make(chan struct{}, concurrency)
+
+	for _, chunk := range chunks {
+		wg.Add(1)
+		go func(chunk []string) {
+			defer wg.Done()
+
+			var (
+				chunkResults map[string]*ResolveResult
+				err          error
+			)
+			select {
+			case sem <- struct{}{}:
+				chunkResults, err = r.resolveBatchChunk(ctx, chunk, options)
+				<-sem
+			case <-ctx.Done():
+				err = ctx.Err()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			for _, domain := range chunk {
+				switch {
+				case err != nil:
+					r.recordFailure(domain, err)
+					results[domain] = newErrorResult(domain, options.ClientIP, NewHTTPDNSError("resolve_batch", domain, err))
+				case chunkResults[domain] != nil:
+					results[domain] = chunkResults[domain]
+				default:
+					// 服务端响应中缺少该域名
+					r.recordFailure(domain, ErrNoResult)
+					results[domain] = newErrorResult(domain, options.ClientIP, NewHTTPDNSError("resolve_batch", domain, ErrNoResult))
+				}
+			}
+		}(chunk)
 	}
 
+	wg.Wait()
+
+	return results
+}
+
+// resolveBatchChunk 执行单次批量请求（域名数不超过 MaxBatchDomains）并更新缓存
+func (r *Resolver) resolveBatchChunk(ctx context.Context, domains []string, options *ResolveOptions) (map[string]*ResolveResult, error) {
 	// 执行HTTP请求（每次重试都会获取新的服务IP并构建URL）
 	builder := NewRequestBuilder(r.config, r.httpClient.authManager)
-	resp, err := r.httpClient.DoRequestWithRetry(ctx, func() (string, error) {
+	resp, err := r.httpClient.DoRequestWithPolicy(ctx, options.RetryPolicy, func() (string, error) {
 		serviceIP, err := r.httpClient.GetAvailableServiceIP()
 		if err != nil {
 			return "", err
 		}
-		return builder.BuildBatchResolveURL(serviceIP, uncachedDomains, options.ClientIP), nil
+		return builder.BatchResolveURL(serviceIP, domains, options.ClientIP, options.QueryType)
 	})
 	if err != nil {
-		// 记录错误指标
-		r.metrics.RecordError(err)
-		latency := time.Since(startTime)
-		r.metrics.RecordResolve(false, latency, SourceHTTPDNS)
-		return nil, NewHTTPDNSError("resolve_batch", "", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	// 解析响应
 	var batchResp BatchResolveResponse
 	if err := json.NewDecoder(resp.Body).Decode(&batchResp); err != nil {
-		// 记录错误指标
-		r.metrics.RecordError(err)
-		latency := time.Since(startTime)
-		r.metrics.RecordResolve(false, latency, SourceHTTPDNS)
-		return nil, NewHTTPDNSError("resolve_batch", "", err)
+		return nil, err
 	}
 
 	// 使用map来合并同一域名的多条记录
@@ -263,8 +341,8 @@ func (r *Resolver) ResolveBatch(ctx context.Context, domains []string, opts ...R
 	timestamp := time.Now()
 
 	for _, dnsResp := range batchResp.DNS {
-		domain := dnsResp.Host
-		
+		domain := normalizeDomain(dnsResp.Host)
+
 		// 如果域名还没有结果，创建新的结果
 		if domainResults[domain] == nil {
 			domainResults[domain] = &ResolveResult{
@@ -276,7 +354,7 @@ func (r *Resolver) ResolveBatch(ctx context.Context, domains []string, opts ...R
 				IPv6:      make([]net.IP, 0),
 			}
 		}
-		
+
 		result := domainResults[domain]
 
 		// 处理 IPv4 地址
@@ -294,32 +372,72 @@ func (r *Resolver) ResolveBatch(ctx context.Context, domains []string, opts ...R
 		}
 
 		// 设置TTL（使用最大的TTL值）
-		if dnsResp.TTL > 0 {
-			newTTL := time.Duration(dnsResp.TTL) * time.Second
+		if ttl := r.config.ttlPolicy(domain).selectTTL(&dnsResp); ttl > 0 {
+			newTTL := time.Duration(ttl) * time.Second
 			if newTTL > result.TTL {
 				result.TTL = newTTL
-				domainTTLs[domain] = dnsResp.TTL
+				domainTTLs[domain] = ttl
 			}
 		}
 	}
 
-	// 更新缓存并转换为结果列表
-	networkResults := make([]*ResolveResult, 0, len(uncachedDomains))
-	for _, domain := range uncachedDomains {
-		if result, ok := domainResults[domain]; ok {
-			r.updateCache(domain, result, domainTTLs[domain])
-			networkResults = append(networkResults, result)
+	// 只保留本次请求的域名并更新缓存
+	chunkResults := make(map[string]*ResolveResult, len(domains))
+	for _, domain := range domains {
+		result, ok := domainResults[domain]
+		if !ok {
+			continue
+		}
+		stored, err := r.storeAnswer(domain, options.QueryType, result, domainTTLs[domain], true)
+		if err != nil {
+			stored = newErrorResult(domain, options.ClientIP, NewHTTPDNSError("resolve_batch", domain, err))
 		}
+		chunkResults[domain] = stored
 	}
 
-	// 合并缓存结果和网络结果
-	allResults := append(cachedResults, networkResults...)
+	return chunkResults, nil
+}
 
-	// 记录成功指标
-	latency := time.Since(startTime)
-	r.metrics.RecordResolve(true, latency, SourceHTTPDNS)
+// dedupeDomains 规范化域名并去重（保持首次出现的顺序）
+func dedupeDomains(domains []string) []string {
+	seen := make(map[string]struct{}, len(domains))
+	unique := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		domain = normalizeDomain(domain)
+		if _, ok := seen[domain]; ok {
+			continue
+		}
+		seen[domain] = struct{}{}
+		unique = append(unique, domain)
+	}
+	return unique
+}
+
+// newErrorResult 构建携带错误信息的解析结果
+func newErrorResult(domain, clientIP string, err error) *ResolveResult {
+	return &ResolveResult{
+		Domain:    domain,
+		ClientIP:  clientIP,
+		Source:    SourceHTTPDNS,
+		Timestamp: time.Now(),
+		Error:     err,
+	}
+}
 
-	return allResults, nil
+// splitDomains 将域名列表按 size 拆分为多个分片
+func splitDomains(domains []string, size int) [][]string {
+	if size <= 0 {
+		size = DefaultMaxBatchDomains
+	}
+	chunks := make([][]string, 0, (len(domains)+size-1)/size)
+	for start := 0; start < len(domains); start += size {
+		end := start + size
+		if end > len(domains) {
+			end = len(domains)
+		}
+		chunks = append(chunks, domains[start:end])
+	}
+	return chunks
 }
 
 // ResolveAsync 异步解析域名
@@ -330,6 +448,53 @@ func (r *Resolver) ResolveAsync(ctx context.Context, domain string, callback fun
 	}()
 }
 
+// newResolveOptions 构建解析选项（先填充默认值，再依次应用用户选项）
+func (r *Resolver) newResolveOptions(opts []ResolveOption) *ResolveOptions {
+	options := &ResolveOptions{
+		QueryType: QueryBoth, // 默认查询IPv4和IPv6
+		Timeout:   r.config.Timeout,
+	}
+
+	for _, opt := range opts {
+		opt(options)
+	}
+	if options.RetryPolicy == nil {
+		options.RetryPolicy = r.config.RetryPolicy
+	}
+
+	return options
+}
+
+// staleWindows 计算本次调用的 stale-while-revalidate 与 stale-if-error 窗口（<0 表示不限制）
+func (r *Resolver) staleWindows(options *ResolveOptions) (whileRevalidate, ifError time.Duration) {
+	whileRevalidate = r.cacheManager.DefaultMaxStale()
+	if options.MaxStale != nil {
+		whileRevalidate = *options.MaxStale
+	}
+
+	ifError = r.config.MaxStaleIfError
+	if whileRevalidate < 0 || whileRevalidate > ifError {
+		ifError = whileRevalidate
+	}
+	return whileRevalidate, ifError
+}
+
+// staleIfError 网络失败时返回窗口内的过期缓存结果，没有可用缓存时返回 nil
+func (r *Resolver) staleIfError(domain, clientIP string, maxStale time.Duration, cause error) *ResolveResult {
+	entry, ok := r.cacheManager.GetStale(domain, maxStale)
+	if !ok {
+		return nil
+	}
+
+	if r.config.Logger != nil {
+		r.config.Logger.Printf("Serving stale cache for %s after resolve failure: %v", domain, cause)
+	}
+
+	result := entry.ToResolveResult(domain)
+	result.ClientIP = clientIP
+	return result
+}
+
 // ValidateDomain 验证域名格式
 func ValidateDomain(domain string) error {
 	if domain == "" {
@@ -346,7 +511,13 @@ func ValidateDomain(domain string) error {
 
 // GetMetrics 获取指标统计
 func (r *Resolver) GetMetrics() MetricsStats {
-	return r.metrics.GetStats()
+	stats := r.metrics.GetStats()
+	if r.config.EnableMetrics {
+		stats.NegativeCacheEntries = int64(r.cacheManager.NegativeCount())
+		stats.ClockSkew = r.httpClient.ClockSkew()
+		stats.HedgedRequests, stats.HedgeWins = r.httpClient.HedgeStats()
+	}
+	return stats
 }
 
 // ResetMetrics 重置指标统计
@@ -399,47 +570,245 @@ func (r *Resolver) tryAsyncUpdate(domain string, fn func()) {
 	}()
 }
 
-// asyncUpdate 异步更新缓存
-func (r *Resolver) asyncUpdate(ctx context.Context, domain, clientIP string, queryType QueryType) {
+// RefreshAhead 批量刷新已超过刷新阈值的热点缓存条目
+func (r *Resolver) RefreshAhead() {
+	if domains := r.cacheManager.RefreshAheadCandidates(); len(domains) > 0 {
+		r.refreshBatch(context.Background(), domains)
+	}
+}
+
+// Refresh 忽略缓存、负缓存和异常结果保护，通过网络重新解析域名并更新缓存
+func (r *Resolver) Refresh(ctx context.Context, domain string, opts ...ResolveOption) (*ResolveResult, error) {
+	if err := ValidateDomain(domain); err != nil {
+		return nil, NewHTTPDNSError("validate_domain", domain, err)
+	}
+	domain = normalizeDomain(domain)
+	options := r.newResolveOptions(opts)
+
+	if options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.Timeout)
+		defer cancel()
+	}
+
+	// 强制刷新以服务端结果为准，不使用异常结果保护
+	r.cacheManager.ClearNegative(domain)
+	result, ttl, err := r.doFetchSingle(ctx, domain, options)
+	if err == nil {
+		r.clearAnomaly(domain)
+		result, err = r.storeAnswer(domain, options.QueryType, result, ttl, false)
+	} else {
+		r.recordFailure(domain, err)
+	}
+	if err != nil {
+		r.metrics.RecordError(err)
+		return nil, NewHTTPDNSError("refresh", domain, err)
+	}
+	return result, nil
+}
+
+// Warmup 按历史访问频率批量刷新缓存缺失或已过期的热点域名，返回成功刷新的域名数
+func (r *Resolver) Warmup(ctx context.Context) int {
+	domains := r.cacheManager.WarmupCandidates(r.config.WarmupTopN)
+	if len(domains) == 0 {
+		return 0
+	}
+
+	if r.config.Logger != nil {
+		r.config.Logger.Printf("Warming up %d domains from persisted cache", len(domains))
+	}
+
+	// 每轮刷新的域名数不超过一次并发能处理的批量请求，保证每轮都有完整的超时时间
+	roundSize := r.config.MaxBatchDomains * r.config.BatchConcurrency
+	if roundSize <= 0 {
+		roundSize = DefaultMaxBatchDomains * DefaultBatchConcurrency
+	}
+
+	refreshed := 0
+	for _, round := range splitDomains(domains, roundSize) {
+		if ctx.Err() != nil {
+			break
+		}
+		refreshed += r.refreshBatch(ctx, round)
+	}
+	return refreshed
+}
+
+// refreshBatch 在后台通过批量请求刷新域名缓存（跳过正在刷新的域名），返回成功刷新的域名数
+func (r *Resolver) refreshBatch(ctx context.Context, domains []string) int {
+	r.updateMu.Lock()
+	pending := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		if !r.updating[domain] {
+			r.updating[domain] = true
+			pending = append(pending, domain)
+		}
+	}
+	r.updateMu.Unlock()
+
+	if len(pending) == 0 {
+		return 0
+	}
+
+	defer func() {
+		r.updateMu.Lock()
+		for _, domain := range pending {
+			delete(r.updating, domain)
+		}
+		r.updateMu.Unlock()
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, r.config.Timeout)
+	defer cancel()
+
+	if err := r.httpClient.UpdateServiceIPsIfNeeded(ctx); err != nil {
+		if r.config.Logger != nil {
+			r.config.Logger.Printf("Background refresh of %d domains failed: %v", len(pending), err)
+		}
+		return 0
+	}
+
+	options := r.newResolveOptions(nil)
+	options.RetryPolicy = r.config.AsyncRetryPolicy
+	results := r.resolveBatchChunks(ctx, splitDomains(pending, r.config.MaxBatchDomains), options)
+
+	failed := 0
+	for _, result := range results {
+		if result.Error != nil {
+			failed++
+		}
+	}
+	if r.config.Logger != nil {
+		r.config.Logger.Printf("Background refresh completed: %d domains, %d failed", len(pending), failed)
+	}
+	return len(pending) - failed
+}
+
+// asyncUpdate 异步更新缓存，使用后台刷新的重试策略
+func (r *Resolver) asyncUpdate(domain string, options *ResolveOptions) {
 	// 创建新的上下文，避免使用已取消的上下文
 	asyncCtx, cancel := context.WithTimeout(context.Background(), r.config.Timeout)
 	defer cancel()
 
-	// 确保有可用的服务IP
-	if err := r.httpClient.UpdateServiceIPsIfNeeded(asyncCtx); err != nil {
+	background := *options
+	background.RetryPolicy = r.config.AsyncRetryPolicy
+	if _, err := r.fetchSingle(asyncCtx, domain, &background); err != nil {
 		if r.config.Logger != nil {
 			r.config.Logger.Printf("Async update failed for %s: %v", domain, err)
 		}
 		return
 	}
 
-	// 执行HTTP请求
+	if r.config.Logger != nil {
+		r.config.Logger.Printf("Async update completed for %s", domain)
+	}
+}
+
+// fetchSingle 通过网络解析单个域名并更新缓存（不查询缓存）
+func (r *Resolver) fetchSingle(ctx context.Context, domain string, options *ResolveOptions) (*ResolveResult, error) {
+	result, ttl, err := r.doFetchSingle(ctx, domain, options)
+	if err != nil {
+		r.recordFailure(domain, err)
+		return nil, err
+	}
+
+	return r.storeAnswer(domain, options.QueryType, result, ttl, true)
+}
+
+// storeAnswer 处理服务端返回的结果：异常结果保护（protect 为 true 时）、负缓存、TTL策略和缓存更新，
+// 返回最终交给调用方的结果
+func (r *Resolver) storeAnswer(domain string, queryType QueryType, result *ResolveResult, ttl int, protect bool) (*ResolveResult, error) {
+	if protect {
+		if kept := r.keepLastKnownGood(domain, queryType, result); kept != nil {
+			return kept, nil
+		}
+	}
+
+	// 启用负缓存时，空结果作为错误返回且不写入正缓存
+	if r.isEmptyAnswer(result) {
+		r.cacheManager.SetNegative(domain, ErrNoAddresses)
+		return nil, ErrNoAddresses
+	}
+
+	// 应用TTL策略，返回生效的TTL和过期时间
+	ttl = r.config.ttlPolicy(domain).apply(ttl)
+	result.TTL = time.Duration(ttl) * time.Second
+	result.ExpiresAt = result.Timestamp.Add(result.TTL)
+
+	r.updateCache(domain, result, ttl)
+	return result, nil
+}
+
+// doFetchSingle 执行单域名解析请求，返回结果和按TTL策略选择的服务端TTL（秒）
+func (r *Resolver) doFetchSingle(ctx context.Context, domain string, options *ResolveOptions) (*ResolveResult, int, error) {
+	// 确保有可用的服务IP
+	if err := r.httpClient.UpdateServiceIPsIfNeeded(ctx); err != nil {
+		return nil, 0, err
+	}
+
+	// 执行HTTP请求（每次重试都会获取新的服务IP并构建URL）
 	builder := NewRequestBuilder(r.config, r.httpClient.authManager)
-	resp, err := r.httpClient.DoRequestWithRetry(asyncCtx, func() (string, error) {
+	resp, err := r.httpClient.DoRequestWithPolicy(ctx, options.RetryPolicy, func() (string, error) {
 		serviceIP, err := r.httpClient.GetAvailableServiceIP()
 		if err != nil {
 			return "", err
 		}
-		return builder.BuildSingleResolveURL(serviceIP, domain, clientIP, queryType), nil
+		return builder.SingleResolveURL(serviceIP, domain, options.ClientIP, options.QueryType)
 	})
 	if err != nil {
-		if r.config.Logger != nil {
-			r.config.Logger.Printf("Async update failed for %s: %v", domain, err)
-		}
-		return
+		return nil, 0, err
 	}
 	defer resp.Body.Close()
 
 	// 解析响应
 	var dnsResp HTTPDNSResponse
 	if err := json.NewDecoder(resp.Body).Decode(&dnsResp); err != nil {
-		if r.config.Logger != nil {
-			r.config.Logger.Printf("Async update parse failed for %s: %v", domain, err)
-		}
+		return nil, 0, err
+	}
+
+	return newResultFromResponse(domain, options.ClientIP, &dnsResp), r.config.ttlPolicy(domain).selectTTL(&dnsResp), nil
+}
+
+// isEmptyAnswer 判断是否为需要进入负缓存的空结果（仅在启用负缓存时生效）
+func (r *Resolver) isEmptyAnswer(result *ResolveResult) bool {
+	return r.cacheManager.NegativeEnabled() && len(result.IPv4) == 0 && len(result.IPv6) == 0
+}
+
+// recordFailure 记录解析失败到负缓存，仅记录针对该域名的服务端结论
+func (r *Resolver) recordFailure(domain string, err error) {
+	if !isNegativeCacheable(err) {
 		return
 	}
+	if ttl := r.cacheManager.SetNegative(domain, err); ttl > 0 && r.config.Logger != nil {
+		r.config.Logger.Printf("Negative caching %s for %v: %v", domain, ttl, err)
+	}
+}
 
-	// 构建结果
+// isNegativeCacheable 判断失败是否可以进入负缓存：服务端对该域名的结论（无地址、缺少结果、域名无效）可以，
+// 超时、网络错误、服务端故障、限流、鉴权失败等暂时性或与域名无关的错误不可以
+func isNegativeCacheable(err error) bool {
+	// 多次尝试的失败以最后一次为准
+	var retryErr *RetryError
+	if errors.As(err, &retryErr) {
+		err = retryErr.LastErr()
+	}
+	if err == nil ||
+		errors.Is(err, context.Canceled) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, ErrNetworkTimeout) {
+		return false
+	}
+	var serverErr *ServerError
+	if errors.As(err, &serverErr) && serverErr.Retryable() {
+		return false
+	}
+	return errors.Is(err, ErrNoAddresses) ||
+		errors.Is(err, ErrNoResult) ||
+		errors.Is(err, ErrInvalidDomain)
+}
+
+// newResultFromResponse 将单域名解析响应转换为 ResolveResult
+func newResultFromResponse(domain, clientIP string, dnsResp *HTTPDNSResponse) *ResolveResult {
 	result := &ResolveResult{
 		Domain:    domain,
 		ClientIP:  clientIP,
@@ -447,26 +816,24 @@ func (r *Resolver) asyncUpdate(ctx context.Context, domain, clientIP string, que
 		Timestamp: time.Now(),
 	}
 
+	// 解析IPv4地址
 	for _, ipStr := range dnsResp.IPs {
 		if ip := net.ParseIP(ipStr); ip != nil {
 			result.IPv4 = append(result.IPv4, ip)
 		}
 	}
 
+	// 解析IPv6地址
 	for _, ipStr := range dnsResp.IPsV6 {
 		if ip := net.ParseIP(ipStr); ip != nil {
 			result.IPv6 = append(result.IPv6, ip)
 		}
 	}
 
+	// 设置TTL
 	if dnsResp.TTL > 0 {
 		result.TTL = time.Duration(dnsResp.TTL) * time.Second
 	}
 
-	// 更新缓存
-	r.updateCache(domain, result, dnsResp.TTL)
-
-	if r.config.Logger != nil {
-		r.config.Logger.Printf("Async update completed for %s", domain)
-	}
+	return result
 }
@@ -3,8 +3,10 @@ package httpdns
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -399,3 +401,147 @@ func TestResolver_ResolveBatch_RealWorldScenarios(t *testing.T) {
 		})
 	}
 }
+
+func TestResolver_ResolveBatch_PerDomainErrors(t *testing.T) {
+	var queryParams []string
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/test123/ss" {
+			serverAddr := server.URL[7:]
+			response := map[string]interface{}{
+				"service_ip": []string{serverAddr},
+			}
+			json.NewEncoder(w).Encode(response)
+		} else if r.URL.Path == "/test123/resolve" {
+			queryParams = append(queryParams, r.URL.Query().Get("query"))
+			// 包含 fail.com 的分片返回错误，其余分片缺少 missing.com
+			if strings.Contains(r.URL.Query().Get("host"), "fail.com") {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			response := BatchResolveResponse{
+				DNS: []HTTPDNSResponse{
+					{Host: "a.com", IPs: []string{"1.1.1.1"}, TTL: 300},
+				},
+			}
+			json.NewEncoder(w).Encode(response)
+		} else {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	config := DefaultConfig()
+	config.AccountID = "test123"
+	config.BootstrapIPs = []string{server.URL[7:]}
+	config.MaxBatchDomains = 2
+
+	resolver := NewResolver(config)
+
+	domains := []string{"a.com", "missing.com", "fail.com"}
+	results, err := resolver.ResolveBatch(context.Background(), domains, WithIPv4Only())
+	if err != nil {
+		t.Fatalf("ResolveBatch() error = %v, want nil for partial failure", err)
+	}
+
+	for _, query := range queryParams {
+		if query != string(QueryIPv4) {
+			t.Errorf("batch request query = %q, want %q", query, QueryIPv4)
+		}
+	}
+
+	if len(results) != 3 {
+		t.Fatalf("ResolveBatch() got %d results, want 3", len(results))
+	}
+	for i, domain := range domains {
+		if results[i].Domain != domain {
+			t.Errorf("results[%d].Domain = %s, want %s", i, results[i].Domain, domain)
+		}
+	}
+	if results[0].Error != nil || len(results[0].IPv4) != 1 {
+		t.Errorf("a.com should resolve, got error %v, ips %v", results[0].Error, results[0].IPv4)
+	}
+	if !errors.Is(results[1].Error, ErrNoResult) {
+		t.Errorf("missing.com error = %v, want ErrNoResult", results[1].Error)
+	}
+	if results[2].Error == nil {
+		t.Error("fail.com should carry the chunk error")
+	}
+}
+
+func TestResolver_ResolveBatch_AllFailed(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/test123/ss" {
+			serverAddr := server.URL[7:]
+			response := map[string]interface{}{
+				"service_ip": []string{serverAddr},
+			}
+			json.NewEncoder(w).Encode(response)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	config := DefaultConfig()
+	config.AccountID = "test123"
+	config.BootstrapIPs = []string{server.URL[7:]}
+
+	resolver := NewResolver(config)
+
+	results, err := resolver.ResolveBatch(context.Background(), []string{"a.com", "b.com"})
+	if err == nil {
+		t.Fatal("ResolveBatch() should return error when all domains failed")
+	}
+	if len(results) != 2 || results[0].Error == nil || results[1].Error == nil {
+		t.Errorf("ResolveBatch() should still return per-domain errors, got %v", results)
+	}
+}
+
+func TestResolver_ResolveBatchMap(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/test123/ss" {
+			serverAddr := server.URL[7:]
+			response := map[string]interface{}{
+				"service_ip": []string{serverAddr},
+			}
+			json.NewEncoder(w).Encode(response)
+		} else if r.URL.Path == "/test123/resolve" {
+			response := BatchResolveResponse{
+				DNS: []HTTPDNSResponse{
+					{Host: "a.com", IPs: []string{"1.1.1.1"}, TTL: 300},
+					{Host: "b.com", IPs: []string{"2.2.2.2"}, TTL: 300},
+				},
+			}
+			json.NewEncoder(w).Encode(response)
+		} else {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	config := DefaultConfig()
+	config.AccountID = "test123"
+	config.BootstrapIPs = []string{server.URL[7:]}
+
+	resolver := NewResolver(config)
+
+	results, err := resolver.ResolveBatchMap(context.Background(), []string{"A.com", "b.com", "a.com"})
+	if err != nil {
+		t.Fatalf("ResolveBatchMap() error = %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("ResolveBatchMap() got %d entries, want 2", len(results))
+	}
+	if r := results["a.com"]; r == nil || len(r.IPv4) != 1 || r.IPv4[0].String() != "1.1.1.1" {
+		t.Errorf("ResolveBatchMap()[a.com] = %v, want 1.1.1.1", r)
+	}
+	if r := results["b.com"]; r == nil || len(r.IPv4) != 1 || r.IPv4[0].String() != "2.2.2.2" {
+		t.Errorf("ResolveBatchMap()[b.com] = %v, want 2.2.2.2", r)
+	}
+}
@@ -3,8 +3,13 @@ package httpdns
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -246,40 +251,165 @@ func TestResolver_ResolveBatch_EmptyDomains(t *testing.T) {
 	}
 }
 
-func TestResolver_ResolveBatch_TooManyDomains(t *testing.T) {
+func TestResolver_ResolveBatch_Chunking(t *testing.T) {
+	var (
+		mu        sync.Mutex
+		batchSize []int
+	)
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/test123/ss" {
+			serverAddr := server.URL[7:]
+			response := map[string]interface{}{
+				"service_ip": []string{serverAddr},
+			}
+			json.NewEncoder(w).Encode(response)
+		} else if r.URL.Path == "/test123/resolve" {
+			hosts := strings.Split(r.URL.Query().Get("host"), ",")
+			mu.Lock()
+			batchSize = append(batchSize, len(hosts))
+			mu.Unlock()
+
+			response := BatchResolveResponse{}
+			for _, host := range hosts {
+				response.DNS = append(response.DNS, HTTPDNSResponse{Host: host, IPs: []string{"1.1.1.1"}, TTL: 300})
+			}
+			json.NewEncoder(w).Encode(response)
+		} else {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name            string
+		maxBatchDomains int
+		domainCount     int
+		wantRequests    int
+	}{
+		{name: "default limit", maxBatchDomains: 0, domainCount: 12, wantRequests: 3},
+		{name: "custom limit", maxBatchDomains: 2, domainCount: 7, wantRequests: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mu.Lock()
+			batchSize = nil
+			mu.Unlock()
+
+			config := DefaultConfig()
+			config.AccountID = "test123"
+			config.BootstrapIPs = []string{server.URL[7:]}
+			config.EnableMemoryCache = false
+			config.MaxBatchDomains = tt.maxBatchDomains
+			if err := config.Validate(); err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+
+			resolver := NewResolver(config)
+
+			domains := make([]string, 0, tt.domainCount)
+			for i := 0; i < tt.domainCount; i++ {
+				domains = append(domains, fmt.Sprintf("domain%d.com", i))
+			}
+
+			results, err := resolver.ResolveBatch(context.Background(), domains)
+			if err != nil {
+				t.Fatalf("ResolveBatch() error = %v", err)
+			}
+			if len(results) != tt.domainCount {
+				t.Errorf("ResolveBatch() got %d results, want %d", len(results), tt.domainCount)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if len(batchSize) != tt.wantRequests {
+				t.Errorf("ResolveBatch() sent %d requests, want %d", len(batchSize), tt.wantRequests)
+			}
+			for _, size := range batchSize {
+				if size > config.MaxBatchDomains {
+					t.Errorf("batch request carried %d domains, limit is %d", size, config.MaxBatchDomains)
+				}
+			}
+		})
+	}
+}
+
+func TestResolver_ResolveBatch_Dedupe(t *testing.T) {
+	var hostParams []string
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/test123/ss" {
+			serverAddr := server.URL[7:]
+			response := map[string]interface{}{
+				"service_ip": []string{serverAddr},
+			}
+			json.NewEncoder(w).Encode(response)
+		} else if r.URL.Path == "/test123/resolve" {
+			hostParams = append(hostParams, r.URL.Query().Get("host"))
+			response := BatchResolveResponse{
+				DNS: []HTTPDNSResponse{
+					{Host: "example.com", IPs: []string{"1.1.1.1"}, TTL: 300},
+					{Host: "test.com", IPs: []string{"2.2.2.2"}, TTL: 300},
+				},
+			}
+			json.NewEncoder(w).Encode(response)
+		} else {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
 	config := DefaultConfig()
 	config.AccountID = "test123"
+	config.BootstrapIPs = []string{server.URL[7:]}
+
 	resolver := NewResolver(config)
 
-	ctx := context.Background()
-	// 创建超过5个域名的列表
-	domains := []string{
-		"domain1.com",
-		"domain2.com",
-		"domain3.com",
-		"domain4.com",
-		"domain5.com",
-		"domain6.com", // 第6个域名，应该触发错误
+	domains := []string{"Example.COM", " example.com. ", "test.com", "", "TEST.com"}
+	results, err := resolver.ResolveBatch(context.Background(), domains)
+	if err != nil {
+		t.Fatalf("ResolveBatch() error = %v", err)
 	}
 
-	_, err := resolver.ResolveBatch(ctx, domains)
-
-	if err == nil {
-		t.Error("ResolveBatch() should return error for too many domains")
+	if len(hostParams) != 1 || hostParams[0] != "example.com,test.com" {
+		t.Errorf("host params = %v, want [example.com,test.com]", hostParams)
 	}
 
-	httpDNSErr, ok := err.(*HTTPDNSError)
-	if !ok {
-		t.Errorf("ResolveBatch() error should be *HTTPDNSError, got %T", err)
-		return
+	// 结果与输入一一对应
+	if len(results) != len(domains) {
+		t.Fatalf("ResolveBatch() got %d results, want %d", len(results), len(domains))
+	}
+	wantDomains := []string{"example.com", "example.com", "test.com", "", "test.com"}
+	for i, want := range wantDomains {
+		if results[i].Domain != want {
+			t.Errorf("results[%d].Domain = %q, want %q", i, results[i].Domain, want)
+		}
+	}
+	if !errors.Is(results[3].Error, ErrInvalidDomain) {
+		t.Errorf("results[3].Error = %v, want ErrInvalidDomain", results[3].Error)
 	}
+	if results[0].Error != nil || results[2].Error != nil {
+		t.Errorf("valid domains should not carry errors: %v, %v", results[0].Error, results[2].Error)
+	}
+}
 
-	if httpDNSErr.Op != "resolve_batch" {
-		t.Errorf("ResolveBatch() error op = %v, want %v", httpDNSErr.Op, "resolve_batch")
+func TestSplitDomains(t *testing.T) {
+	domains := []string{"a.com", "b.com", "c.com", "d.com", "e.com"}
+
+	chunks := splitDomains(domains, 2)
+	if len(chunks) != 3 {
+		t.Fatalf("splitDomains() got %d chunks, want 3", len(chunks))
+	}
+	if len(chunks[2]) != 1 || chunks[2][0] != "e.com" {
+		t.Errorf("splitDomains() last chunk = %v, want [e.com]", chunks[2])
 	}
 
-	if httpDNSErr.Err != ErrTooManyDomains {
-		t.Errorf("ResolveBatch() error should be ErrTooManyDomains, got %v", httpDNSErr.Err)
+	if chunks := splitDomains(domains, 0); len(chunks) != 1 {
+		t.Errorf("splitDomains() with size 0 got %d chunks, want 1", len(chunks))
 	}
 }
 
@@ -429,3 +559,319 @@ func TestValidateDomain(t *testing.T) {
 	}
 }
 
+
+func TestResolver_RefreshAhead(t *testing.T) {
+	var hostParams []string
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/test123/ss" {
+			serverAddr := server.URL[7:]
+			response := map[string]interface{}{
+				"service_ip": []string{serverAddr},
+			}
+			json.NewEncoder(w).Encode(response)
+		} else if r.URL.Path == "/test123/resolve" {
+			hostParams = append(hostParams, r.URL.Query().Get("host"))
+			response := BatchResolveResponse{
+				DNS: []HTTPDNSResponse{
+					{Host: "hot.com", IPs: []string{"9.9.9.9"}, TTL: 300},
+				},
+			}
+			json.NewEncoder(w).Encode(response)
+		} else {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	config := DefaultConfig()
+	config.AccountID = "test123"
+	config.BootstrapIPs = []string{server.URL[7:]}
+	config.EnableRefreshAhead = true
+
+	resolver := NewResolver(config)
+	resolver.cacheManager.Set("hot.com", &CacheEntry{
+		IPv4:      []string{"1.1.1.1"},
+		TTL:       100,
+		QueryTime: time.Now().Add(-90 * time.Second),
+	})
+	// 只记录访问：通过 Get 访问会由访问本身触发刷新，定期扫描在刷新间隔内不再重复刷新
+	resolver.cacheManager.recordAccess("hot.com")
+
+	resolver.RefreshAhead()
+
+	if len(hostParams) != 1 || hostParams[0] != "hot.com" {
+		t.Fatalf("RefreshAhead() batch hosts = %v, want [hot.com]", hostParams)
+	}
+	entry, hit, _ := resolver.cacheManager.Get("hot.com")
+	if !hit || entry.IPv4[0] != "9.9.9.9" {
+		t.Errorf("cache should be refreshed, got %v", entry)
+	}
+}
+
+func TestResolver_StaleIfError(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/test123/ss" {
+			serverAddr := server.URL[7:]
+			response := map[string]interface{}{
+				"service_ip": []string{serverAddr},
+			}
+			json.NewEncoder(w).Encode(response)
+		} else {
+			// 解析接口始终失败
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name            string
+		maxStaleIfError time.Duration
+		wantStale       bool
+	}{
+		{name: "within stale-if-error window", maxStaleIfError: time.Hour, wantStale: true},
+		{name: "beyond stale-if-error window", maxStaleIfError: 10 * time.Minute, wantStale: false},
+		{name: "stale-if-error disabled", maxStaleIfError: 0, wantStale: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.AccountID = "test123"
+			config.BootstrapIPs = []string{server.URL[7:]}
+			config.MaxStaleWhileRevalidate = time.Minute
+			config.MaxStaleIfError = tt.maxStaleIfError
+
+			resolver := NewResolver(config)
+			// 过期 30 分钟，超出 stale-while-revalidate 窗口
+			resolver.cacheManager.Set("example.com", &CacheEntry{
+				IPv4:      []string{"1.2.3.4"},
+				TTL:       60,
+				QueryTime: time.Now().Add(-31 * time.Minute),
+			})
+
+			result, err := resolver.ResolveSingle(context.Background(), "example.com")
+			if tt.wantStale {
+				if err != nil {
+					t.Fatalf("ResolveSingle() error = %v, want stale result", err)
+				}
+				if !result.Stale || len(result.IPv4) != 1 {
+					t.Errorf("ResolveSingle() = %+v, want stale result with cached IP", result)
+				}
+			} else if err == nil {
+				t.Errorf("ResolveSingle() = %+v, want error", result)
+			}
+
+			results, _ := resolver.ResolveBatch(context.Background(), []string{"example.com"})
+			if gotStale := len(results) == 1 && results[0].Error == nil && results[0].Stale; gotStale != tt.wantStale {
+				t.Errorf("ResolveBatch() stale fallback = %v, want %v", gotStale, tt.wantStale)
+			}
+		})
+	}
+}
+
+func TestResolver_WithMaxStale(t *testing.T) {
+	requestCount := 0
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/test123/ss" {
+			serverAddr := server.URL[7:]
+			response := map[string]interface{}{
+				"service_ip": []string{serverAddr},
+			}
+			json.NewEncoder(w).Encode(response)
+		} else if r.URL.Path == "/test123/d" {
+			requestCount++
+			response := HTTPDNSResponse{
+				Host: "example.com",
+				IPs:  []string{"5.6.7.8"},
+				TTL:  60,
+			}
+			json.NewEncoder(w).Encode(response)
+		}
+	}))
+	defer server.Close()
+
+	config := DefaultConfig()
+	config.AccountID = "test123"
+	config.BootstrapIPs = []string{server.URL[7:]}
+
+	resolver := NewResolver(config)
+	resolver.cacheManager.Set("example.com", &CacheEntry{
+		IPv4:      []string{"1.2.3.4"},
+		TTL:       60,
+		QueryTime: time.Now().Add(-90 * time.Second),
+	})
+
+	// 默认不接受过期缓存时，仍可按调用放宽
+	result, err := resolver.ResolveSingle(context.Background(), "example.com", WithMaxStale(time.Minute))
+	if err != nil {
+		t.Fatalf("ResolveSingle() error = %v", err)
+	}
+	if !result.Stale || result.IPv4[0].String() != "1.2.3.4" {
+		t.Errorf("ResolveSingle() = %v stale=%v, want stale cached IP", result.IPv4, result.Stale)
+	}
+}
+
+func TestResolver_NegativeCache(t *testing.T) {
+	var requestCount int32
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/test123/ss" {
+			serverAddr := server.URL[7:]
+			response := map[string]interface{}{
+				"service_ip": []string{serverAddr},
+			}
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		atomic.AddInt32(&requestCount, 1)
+		switch {
+		case strings.Contains(r.URL.RawQuery, "fail.example.com"):
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"code": "InvalidHost"})
+			return
+		case strings.Contains(r.URL.RawQuery, "down.example.com"):
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		case strings.Contains(r.URL.RawQuery, "slow.example.com"):
+			select {
+			case <-time.After(time.Second):
+			case <-r.Context().Done():
+			}
+			return
+		}
+		// 返回空结果
+		response := HTTPDNSResponse{Host: "empty.example.com", TTL: 60}
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	config := DefaultConfig()
+	config.AccountID = "test123"
+	config.BootstrapIPs = []string{server.URL[7:]}
+	config.EnableMetrics = true
+	config.NegativeCacheTTL = time.Minute
+	config.RetryPolicy = NewExponentialBackoff(0)
+
+	resolver := NewResolver(config)
+
+	// 暂时性错误（5xx、超时）不进入负缓存，每次都请求网络
+	for _, domain := range []string{"down.example.com", "slow.example.com"} {
+		for i := 0; i < 2; i++ {
+			_, err := resolver.ResolveSingle(context.Background(), domain, WithTimeout(50*time.Millisecond))
+			if err == nil {
+				t.Fatalf("ResolveSingle(%s) error = nil, want error", domain)
+			}
+			var httpDNSErr *HTTPDNSError
+			if errors.As(err, &httpDNSErr) && httpDNSErr.Op == "negative_cache" {
+				t.Errorf("ResolveSingle(%s) error = %v, want no negative cache for transient errors", domain, err)
+			}
+		}
+	}
+	if got := atomic.SwapInt32(&requestCount, 0); got != 4 {
+		t.Errorf("network requests for transient errors = %d, want 4", got)
+	}
+	if got := resolver.cacheManager.NegativeCount(); got != 0 {
+		t.Errorf("NegativeCount() after transient errors = %d, want 0", got)
+	}
+
+	for _, domain := range []string{"empty.example.com", "fail.example.com"} {
+		for i := 0; i < 3; i++ {
+			_, err := resolver.ResolveSingle(context.Background(), domain)
+			if err == nil {
+				t.Fatalf("ResolveSingle(%s) error = nil, want error", domain)
+			}
+			if domain == "empty.example.com" && !errors.Is(err, ErrNoAddresses) {
+				t.Errorf("ResolveSingle(%s) error = %v, want ErrNoAddresses", domain, err)
+			}
+		}
+	}
+
+	// 每个域名只访问一次网络，后续命中负缓存
+	if got := atomic.LoadInt32(&requestCount); got != 2 {
+		t.Errorf("network requests = %d, want 2", got)
+	}
+
+	results, err := resolver.ResolveBatch(context.Background(), []string{"empty.example.com"})
+	if err == nil || len(results) != 1 || !errors.Is(results[0].Error, ErrNoAddresses) {
+		t.Errorf("ResolveBatch() = %+v, %v, want negative cache error", results, err)
+	}
+	if got := atomic.LoadInt32(&requestCount); got != 2 {
+		t.Errorf("network requests after batch = %d, want 2", got)
+	}
+
+	stats := resolver.GetMetrics()
+	if stats.NegativeCacheHits != 5 {
+		t.Errorf("NegativeCacheHits = %d, want 5", stats.NegativeCacheHits)
+	}
+	if stats.NegativeCacheEntries != 2 {
+		t.Errorf("NegativeCacheEntries = %d, want 2", stats.NegativeCacheEntries)
+	}
+}
+
+func TestResolver_Warmup(t *testing.T) {
+	var batchRequests int32
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/test123/ss" {
+			serverAddr := server.URL[7:]
+			response := map[string]interface{}{
+				"service_ip": []string{serverAddr},
+			}
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		atomic.AddInt32(&batchRequests, 1)
+		var dns []HTTPDNSResponse
+		for _, host := range strings.Split(r.URL.Query().Get("host"), ",") {
+			dns = append(dns, HTTPDNSResponse{Host: host, IPs: []string{"1.2.3.4"}, TTL: 60})
+		}
+		json.NewEncoder(w).Encode(BatchResolveResponse{DNS: dns})
+	}))
+	defer server.Close()
+
+	config := DefaultConfig()
+	config.AccountID = "test123"
+	config.BootstrapIPs = []string{server.URL[7:]}
+	config.WarmupTopN = 6
+	config.MaxBatchDomains = 2
+
+	resolver := NewResolver(config)
+
+	// 模拟从磁盘恢复的访问统计
+	records := make(map[string]*AccessStats)
+	for i := 0; i < 8; i++ {
+		records[fmt.Sprintf("d%d.example.com", i)] = &AccessStats{Hits: int64(10 - i), LastAccess: time.Now()}
+	}
+	resolver.cacheManager.restoreAccessStats(records)
+
+	if got := resolver.Warmup(context.Background()); got != 6 {
+		t.Errorf("Warmup() = %d, want 6", got)
+	}
+	if got := atomic.LoadInt32(&batchRequests); got != 3 {
+		t.Errorf("batch requests = %d, want 3", got)
+	}
+
+	// 访问最多的前6个域名已预热
+	for i := 0; i < 8; i++ {
+		domain := fmt.Sprintf("d%d.example.com", i)
+		_, ok := resolver.cacheManager.GetStale(domain, -1)
+		if want := i < 6; ok != want {
+			t.Errorf("%s cached = %v, want %v", domain, ok, want)
+		}
+	}
+
+	// 已预热的域名不再重复刷新
+	if got := resolver.Warmup(context.Background()); got != 0 {
+		t.Errorf("second Warmup() = %d, want 0", got)
+	}
+}
@@ -0,0 +1,125 @@
+package httpdns
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// 指数退避默认参数
+const (
+	DefaultRetryBaseDelay  = 200 * time.Millisecond
+	DefaultRetryMaxDelay   = 5 * time.Second
+	DefaultRetryMultiplier = 2.0
+	DefaultRetryJitter     = 0.2
+)
+
+// RetryPolicy 请求重试策略
+type RetryPolicy interface {
+	// MaxAttempts 返回最大尝试次数（包含首次请求），小于1时按1处理
+	MaxAttempts() int
+	// Retryable 判断失败的尝试是否可以重试
+	Retryable(err error) bool
+	// Backoff 返回第 retry 次重试（从1开始）前的等待时长
+	Backoff(retry int) time.Duration
+}
+
+// ExponentialBackoff 带随机抖动和上限的指数退避重试策略
+// BaseDelay、MaxDelay、Multiplier 为零值时使用默认值，Jitter 为0时不抖动
+type ExponentialBackoff struct {
+	MaxRetries int              // 最大重试次数（不含首次请求）
+	BaseDelay  time.Duration    // 首次重试前的等待时长，默认200毫秒
+	MaxDelay   time.Duration    // 等待时长上限，默认5秒
+	Multiplier float64          // 每次重试等待时长的增长倍数，默认2
+	Jitter     float64          // 随机抖动比例 [0,1]，等待时长在 [d*(1-Jitter), d] 内随机
+	RetryIf    func(error) bool // 自定义可重试判断，默认 IsRetryable
+}
+
+// NewExponentialBackoff 创建使用默认退避参数的指数退避策略
+func NewExponentialBackoff(maxRetries int) *ExponentialBackoff {
+	return &ExponentialBackoff{
+		MaxRetries: maxRetries,
+		BaseDelay:  DefaultRetryBaseDelay,
+		MaxDelay:   DefaultRetryMaxDelay,
+		Multiplier: DefaultRetryMultiplier,
+		Jitter:     DefaultRetryJitter,
+	}
+}
+
+// MaxAttempts 返回最大尝试次数
+func (p *ExponentialBackoff) MaxAttempts() int {
+	if p.MaxRetries < 0 {
+		return 1
+	}
+	return p.MaxRetries + 1
+}
+
+// Retryable 判断失败的尝试是否可以重试
+func (p *ExponentialBackoff) Retryable(err error) bool {
+	if p.RetryIf != nil {
+		return p.RetryIf(err)
+	}
+	return IsRetryable(err)
+}
+
+// Backoff 返回第 retry 次重试前的等待时长
+func (p *ExponentialBackoff) Backoff(retry int) time.Duration {
+	base, maxDelay, multiplier := p.BaseDelay, p.MaxDelay, p.Multiplier
+	if base <= 0 {
+		base = DefaultRetryBaseDelay
+	}
+	if maxDelay <= 0 {
+		maxDelay = DefaultRetryMaxDelay
+	}
+	if multiplier < 1 {
+		multiplier = DefaultRetryMultiplier
+	}
+
+	delay := float64(base)
+	for i := 1; i < retry && delay < float64(maxDelay); i++ {
+		delay *= multiplier
+	}
+	if delay > float64(maxDelay) {
+		delay = float64(maxDelay)
+	}
+
+	if jitter := p.Jitter; jitter > 0 {
+		if jitter > 1 {
+			jitter = 1
+		}
+		delay -= delay * jitter * rand.Float64()
+	}
+	return time.Duration(delay)
+}
+
+// IsRetryable 默认的可重试判断：网络错误和服务端故障可以重试，
+// 鉴权失败、请求错误、限流、时钟偏差和上下文取消不重试
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrClockSkew) {
+		return false
+	}
+	var serverErr *ServerError
+	if errors.As(err, &serverErr) {
+		return serverErr.Retryable()
+	}
+	return true
+}
+
+// sleepContext 等待 d 或直到 ctx 结束，ctx 结束时返回其错误
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
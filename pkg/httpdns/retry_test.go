@@ -0,0 +1,129 @@
+package httpdns
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestExponentialBackoff_Backoff(t *testing.T) {
+	policy := &ExponentialBackoff{MaxRetries: 3, BaseDelay: 100 * time.Millisecond, MaxDelay: 300 * time.Millisecond}
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond, 300 * time.Millisecond}
+	for i, w := range want {
+		if got := policy.Backoff(i + 1); got != w {
+			t.Errorf("Backoff(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+	if policy.MaxAttempts() != 4 {
+		t.Errorf("MaxAttempts() = %d, want 4", policy.MaxAttempts())
+	}
+
+	// 零值参数使用默认值
+	if got := (&ExponentialBackoff{}).Backoff(2); got != DefaultRetryBaseDelay*2 {
+		t.Errorf("zero value Backoff(2) = %v, want %v", got, DefaultRetryBaseDelay*2)
+	}
+	if got := (&ExponentialBackoff{MaxRetries: -1}).MaxAttempts(); got != 1 {
+		t.Errorf("MaxAttempts() with negative retries = %d, want 1", got)
+	}
+
+	// 抖动范围 [d*(1-Jitter), d]
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if got := policy.Backoff(2); got < 100*time.Millisecond || got > 200*time.Millisecond {
+			t.Fatalf("Backoff(2) with jitter = %v, want within [100ms, 200ms]", got)
+		}
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"network error", NewHTTPDNSError("http_request", "", errors.New("connection refused")), true},
+		{"server error", NewHTTPDNSError("http_status", "", &ServerError{StatusCode: 503, kind: serverErrorKind{ErrServiceUnavailable, ErrServiceUnavailable}}), true},
+		{"auth failure", NewHTTPDNSError("http_status", "", &ServerError{StatusCode: 403, kind: serverErrorCatalog["InvalidAccount"]}), false},
+		{"bad request", &ServerError{StatusCode: 400, kind: serverErrorKind{ErrBadRequest, ErrBadRequest}}, false},
+		{"rate limited", &ServerError{StatusCode: 429, kind: serverErrorKind{ErrRateLimited, ErrRateLimited}}, false},
+		{"clock skew", NewHTTPDNSError("signature_rejected", "", ErrClockSkew), false},
+		{"canceled", context.Canceled, false},
+		{"deadline", NewHTTPDNSError("http_request", "", context.DeadlineExceeded), false},
+		{"nil", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryable(tt.err); got != tt.want {
+				t.Errorf("IsRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+
+	policy := &ExponentialBackoff{RetryIf: func(err error) bool { return errors.Is(err, ErrRateLimited) }}
+	if !policy.Retryable(ErrRateLimited) || policy.Retryable(ErrServiceUnavailable) {
+		t.Error("Retryable() should use RetryIf when set")
+	}
+}
+
+func TestResolver_RetryPolicies(t *testing.T) {
+	var resolves, fetches int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/ss") {
+			// 前两次获取服务IP失败
+			if atomic.AddInt32(&fetches, 1) <= 2 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"service_ip":["` + r.Host + `"]}`))
+			return
+		}
+		atomic.AddInt32(&resolves, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	fast := func(retries int) *ExponentialBackoff {
+		return &ExponentialBackoff{MaxRetries: retries, BaseDelay: time.Millisecond}
+	}
+	config := DefaultConfig()
+	config.AccountID = "test123"
+	config.BootstrapIPs = []string{server.URL[7:]}
+	config.EnableMemoryCache = false
+	config.RetryPolicy = fast(2)
+	config.AsyncRetryPolicy = fast(0)
+	config.ServiceIPRetryPolicy = fast(2)
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	resolver := NewResolver(config)
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		resolve func()
+		want    int32
+	}{
+		{"foreground", func() { resolver.ResolveSingle(ctx, "example.com") }, 3},
+		{"per call", func() { resolver.ResolveSingle(ctx, "example.com", WithRetryPolicy(fast(1))) }, 2},
+		{"async", func() { resolver.asyncUpdate("example.com", resolver.newResolveOptions(nil)) }, 1},
+		{"batch", func() { resolver.ResolveBatch(ctx, []string{"a.com", "b.com"}) }, 3},
+	}
+	for _, tt := range tests {
+		atomic.StoreInt32(&resolves, 0)
+		tt.resolve()
+		if got := atomic.LoadInt32(&resolves); got != tt.want {
+			t.Errorf("%s: resolve requests = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+
+	// 服务IP获取失败两次后第三次成功
+	if got := atomic.LoadInt32(&fetches); got != 3 {
+		t.Errorf("service IP fetches = %d, want 3", got)
+	}
+}
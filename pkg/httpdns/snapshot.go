@@ -0,0 +1,120 @@
+package httpdns
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// CacheSnapshotVersion 当前缓存快照格式版本
+const CacheSnapshotVersion = 1
+
+// CacheSnapshot 可移植的缓存快照，不依赖账号和本地缓存目录
+type CacheSnapshot struct {
+	Version   int                     `json:"version"`
+	CreatedAt time.Time               `json:"created_at"`
+	Records   map[string]*CacheEntry  `json:"records"`
+	Stats     map[string]*AccessStats `json:"stats,omitempty"`
+}
+
+// ConflictPolicy 导入时与已有条目冲突的处理方式
+type ConflictPolicy int
+
+const (
+	ConflictKeepNewer    ConflictPolicy = iota // 保留查询时间较新的条目（默认）
+	ConflictKeepExisting                       // 保留已有条目
+	ConflictOverwrite                          // 使用快照中的条目覆盖
+)
+
+// ImportPolicy 缓存快照导入策略
+type ImportPolicy struct {
+	KeepExpired bool           // 是否保留已过期的条目（可用于过期缓存兜底和启动预热），默认丢弃
+	Conflict    ConflictPolicy // 冲突处理方式
+}
+
+// Export 将内存缓存和访问统计写入快照
+func (c *CacheManager) Export(w io.Writer) error {
+	if !c.enabled {
+		return ErrCacheDisabled
+	}
+
+	c.cacheMutex.RLock()
+	records := make(map[string]*CacheEntry, len(c.cache))
+	for domain, entry := range c.cache {
+		records[domain] = entry
+	}
+	c.cacheMutex.RUnlock()
+
+	snapshot := CacheSnapshot{
+		Version:   CacheSnapshotVersion,
+		CreatedAt: time.Now(),
+		Records:   records,
+		Stats:     c.snapshotAccessStats(),
+	}
+	return json.NewEncoder(w).Encode(snapshot)
+}
+
+// Import 从快照导入缓存，返回导入的条目数
+func (c *CacheManager) Import(r io.Reader, policy ImportPolicy) (int, error) {
+	if !c.enabled {
+		return 0, ErrCacheDisabled
+	}
+
+	var snapshot CacheSnapshot
+	if err := json.NewDecoder(r).Decode(&snapshot); err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+	}
+	if snapshot.Version <= 0 || snapshot.Version > CacheSnapshotVersion {
+		return 0, fmt.Errorf("%w: version %d", ErrInvalidSnapshot, snapshot.Version)
+	}
+
+	imported := make([]string, 0, len(snapshot.Records))
+	now := time.Now()
+
+	c.cacheMutex.Lock()
+	for domain, entry := range snapshot.Records {
+		if entry == nil || (len(entry.IPv4) == 0 && len(entry.IPv6) == 0) {
+			continue
+		}
+		if !policy.KeepExpired && entry.IsExpired() {
+			continue
+		}
+		if entry.TTL <= 0 {
+			entry.TTL = defaultCacheTTL
+		}
+
+		domain = normalizeDomain(domain)
+		if existing, ok := c.cache[domain]; ok {
+			switch policy.Conflict {
+			case ConflictKeepExisting:
+				continue
+			case ConflictKeepNewer:
+				if !entry.QueryTime.After(existing.QueryTime) {
+					continue
+				}
+			}
+		}
+
+		// 导入晚于已有的删除，记录导入时间以免合并时被删除标记覆盖
+		entry.ImportedAt = now
+		c.cache[domain] = entry
+		delete(c.tombstones, domain)
+		c.markDirty(domain)
+		imported = append(imported, domain)
+	}
+	c.cacheMutex.Unlock()
+
+	c.restoreAccessStats(snapshot.Stats)
+	for _, domain := range imported {
+		c.ClearNegative(domain)
+	}
+
+	if len(imported) > 0 {
+		if c.logger != nil {
+			c.logger.Printf("Imported %d of %d records from cache snapshot", len(imported), len(snapshot.Records))
+		}
+		c.SaveResolveCacheAsync()
+	}
+	return len(imported), nil
+}
@@ -0,0 +1,215 @@
+package httpdns
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newSnapshotTestCacheManager() *CacheManager {
+	config := DefaultConfig()
+	config.AccountID = "test123"
+	return NewCacheManager(config)
+}
+
+func TestCacheManager_ExportImport(t *testing.T) {
+	src := newSnapshotTestCacheManager()
+	now := time.Now()
+	src.Set("fresh.com", &CacheEntry{IPv4: []string{"1.1.1.1"}, TTL: 60, QueryTime: now})
+	src.Set("expired.com", &CacheEntry{IPv4: []string{"2.2.2.2"}, TTL: 60, QueryTime: now.Add(-time.Hour)})
+	src.Get("fresh.com")
+
+	var buf bytes.Buffer
+	if err := src.Export(&buf); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	var snapshot CacheSnapshot
+	if err := json.Unmarshal(buf.Bytes(), &snapshot); err != nil {
+		t.Fatalf("snapshot is not valid JSON: %v", err)
+	}
+	if snapshot.Version != CacheSnapshotVersion || len(snapshot.Records) != 2 {
+		t.Fatalf("snapshot = %+v, want version %d with 2 records", snapshot, CacheSnapshotVersion)
+	}
+
+	tests := []struct {
+		name     string
+		policy   ImportPolicy
+		want     int
+		wantKeep bool
+	}{
+		{name: "drop expired", policy: ImportPolicy{}, want: 1, wantKeep: false},
+		{name: "keep expired", policy: ImportPolicy{KeepExpired: true}, want: 2, wantKeep: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := newSnapshotTestCacheManager()
+			got, err := dst.Import(bytes.NewReader(buf.Bytes()), tt.policy)
+			if err != nil {
+				t.Fatalf("Import() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Import() = %d, want %d", got, tt.want)
+			}
+			if _, ok := dst.GetStale("expired.com", -1); ok != tt.wantKeep {
+				t.Errorf("expired.com imported = %v, want %v", ok, tt.wantKeep)
+			}
+			if stats := dst.accessStatsOf("fresh.com"); stats.total != 2 {
+				t.Errorf("imported stats for fresh.com = %+v, want total 2", stats)
+			}
+		})
+	}
+}
+
+func TestCacheManager_ImportPersisted(t *testing.T) {
+	config := DefaultConfig()
+	config.AccountID = "test123"
+	config.EnablePersistentCache = true
+	config.CacheDir = t.TempDir()
+	now := time.Now()
+
+	// 先删除 deleted.com，留下删除标记
+	cm := NewCacheManager(config)
+	cm.Set("deleted.com", &CacheEntry{IPv4: []string{"9.9.9.9"}, TTL: 300, QueryTime: now})
+	cm.SavePending()
+	cm.Delete("deleted.com")
+	cm.SavePending()
+
+	// 导入的记录查询时间早于删除时间，仍应写入持久化缓存
+	src := newSnapshotTestCacheManager()
+	src.Set("fresh.com", &CacheEntry{IPv4: []string{"1.1.1.1"}, TTL: 300, QueryTime: now})
+	src.Set("deleted.com", &CacheEntry{IPv4: []string{"2.2.2.2"}, TTL: 300, QueryTime: now.Add(-time.Minute)})
+	var buf bytes.Buffer
+	if err := src.Export(&buf); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if got, err := cm.Import(&buf, ImportPolicy{}); err != nil || got != 2 {
+		t.Fatalf("Import() = %d, %v, want 2", got, err)
+	}
+	cm.SavePending()
+
+	// 从同一缓存目录重新加载
+	reloaded := NewCacheManager(config)
+	if err := reloaded.LoadFromDisk(); err != nil {
+		t.Fatalf("LoadFromDisk() error = %v", err)
+	}
+	for domain, want := range map[string]string{"fresh.com": "1.1.1.1", "deleted.com": "2.2.2.2"} {
+		entry, ok := reloaded.GetStale(domain, -1)
+		if !ok || len(entry.IPv4) != 1 || entry.IPv4[0] != want {
+			t.Errorf("reloaded %s = %+v, want imported entry %s", domain, entry, want)
+		}
+	}
+}
+
+func TestCacheManager_ImportConflict(t *testing.T) {
+	now := time.Now()
+
+	src := newSnapshotTestCacheManager()
+	src.Set("newer.com", &CacheEntry{IPv4: []string{"9.9.9.9"}, TTL: 60, QueryTime: now})
+	src.Set("older.com", &CacheEntry{IPv4: []string{"9.9.9.9"}, TTL: 600, QueryTime: now.Add(-time.Minute)})
+
+	var buf bytes.Buffer
+	if err := src.Export(&buf); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		conflict  ConflictPolicy
+		wantNewer string
+		wantOlder string
+	}{
+		{name: "keep newer", conflict: ConflictKeepNewer, wantNewer: "9.9.9.9", wantOlder: "1.1.1.1"},
+		{name: "keep existing", conflict: ConflictKeepExisting, wantNewer: "1.1.1.1", wantOlder: "1.1.1.1"},
+		{name: "overwrite", conflict: ConflictOverwrite, wantNewer: "9.9.9.9", wantOlder: "9.9.9.9"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := newSnapshotTestCacheManager()
+			dst.Set("newer.com", &CacheEntry{IPv4: []string{"1.1.1.1"}, TTL: 60, QueryTime: now.Add(-30 * time.Second)})
+			dst.Set("older.com", &CacheEntry{IPv4: []string{"1.1.1.1"}, TTL: 60, QueryTime: now})
+
+			if _, err := dst.Import(bytes.NewReader(buf.Bytes()), ImportPolicy{Conflict: tt.conflict}); err != nil {
+				t.Fatalf("Import() error = %v", err)
+			}
+
+			if entry, _ := dst.GetStale("newer.com", -1); entry.IPv4[0] != tt.wantNewer {
+				t.Errorf("newer.com = %v, want %s", entry.IPv4, tt.wantNewer)
+			}
+			if entry, _ := dst.GetStale("older.com", -1); entry.IPv4[0] != tt.wantOlder {
+				t.Errorf("older.com = %v, want %s", entry.IPv4, tt.wantOlder)
+			}
+		})
+	}
+}
+
+func TestCacheManager_ImportInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "not json", input: "not a snapshot"},
+		{name: "missing version", input: `{"records":{}}`},
+		{name: "future version", input: `{"version":99,"records":{}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cm := newSnapshotTestCacheManager()
+			if _, err := cm.Import(strings.NewReader(tt.input), ImportPolicy{}); !errors.Is(err, ErrInvalidSnapshot) {
+				t.Errorf("Import() error = %v, want ErrInvalidSnapshot", err)
+			}
+		})
+	}
+
+	config := DefaultConfig()
+	config.AccountID = "test123"
+	config.EnableMemoryCache = false
+	disabled := NewCacheManager(config)
+	if err := disabled.Export(&bytes.Buffer{}); !errors.Is(err, ErrCacheDisabled) {
+		t.Errorf("Export() with cache disabled error = %v, want ErrCacheDisabled", err)
+	}
+}
+
+func TestClient_ExportImportCache(t *testing.T) {
+	config := DefaultConfig()
+	config.AccountID = "test123"
+
+	src, err := NewClient(config)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	defer src.Close()
+	src.(*client).cacheManager.Set("example.com", &CacheEntry{IPv4: []string{"1.2.3.4"}, TTL: 60, QueryTime: time.Now()})
+
+	var buf bytes.Buffer
+	if err := src.ExportCache(&buf); err != nil {
+		t.Fatalf("ExportCache() error = %v", err)
+	}
+
+	// 快照不绑定账号，可导入到其他账号的客户端
+	dstConfig := DefaultConfig()
+	dstConfig.AccountID = "test456"
+	dst, err := NewClient(dstConfig)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	defer dst.Close()
+
+	if got, err := dst.ImportCache(&buf, ImportPolicy{}); err != nil || got != 1 {
+		t.Fatalf("ImportCache() = %d, %v, want 1", got, err)
+	}
+	if _, ok := dst.Cache().Get("example.com"); !ok {
+		t.Error("imported entry should be visible through Cache()")
+	}
+
+	dst.Close()
+	if _, err := dst.ImportCache(strings.NewReader("{}"), ImportPolicy{}); err == nil {
+		t.Error("ImportCache() after Close should fail")
+	}
+}
@@ -0,0 +1,596 @@
+package httpdns
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// 持久化文件名
+const (
+	resolveCacheFile   = "resolve_cache.json"
+	resolveJournalFile = "resolve_cache.journal"
+	serviceIPsFile     = "service_ips.json"
+	lockFileName       = ".lock"
+)
+
+// PersistentStore 持久化存储接口
+// Load 方法在数据不存在时返回 nil, nil；实现需保证并发安全
+type PersistentStore interface {
+	// LoadResolveCache 加载解析缓存
+	LoadResolveCache() (*ResolveCacheData, error)
+
+	// SaveResolveCache 保存解析缓存（多进程共享的实现应与已有数据合并）
+	SaveResolveCache(data *ResolveCacheData) error
+
+	// DeleteResolveCache 删除解析缓存
+	DeleteResolveCache() error
+
+	// LoadServiceIPs 加载服务IP缓存
+	LoadServiceIPs() (*ServiceIPCacheData, error)
+
+	// SaveServiceIPs 保存服务IP缓存
+	SaveServiceIPs(data *ServiceIPCacheData) error
+
+	// DeleteServiceIPs 删除服务IP缓存
+	DeleteServiceIPs() error
+}
+
+// JournalStore 支持增量写入的持久化存储（可选接口）
+// 实现该接口后，解析缓存的更新以追加日志的方式写入，SaveResolveCache 负责合并日志（压缩）
+type JournalStore interface {
+	PersistentStore
+
+	// AppendResolveCache 追加解析缓存的增量更新
+	AppendResolveCache(records []JournalRecord) error
+}
+
+// JournalRecord 解析缓存日志记录，Entry 为 nil 表示删除
+type JournalRecord struct {
+	Domain string       `json:"domain"`
+	Entry  *CacheEntry  `json:"entry,omitempty"`
+	Stats  *AccessStats `json:"stats,omitempty"` // 写入时的访问统计，用于启动预热
+	Time   time.Time    `json:"time"`            // 写入或删除时间
+}
+
+// FileStore 基于本地 JSON 文件的持久化存储（默认实现）
+// 读写通过建议性文件锁与同一目录下的其他进程互斥，写入时按查询时间合并其他进程写入的记录
+// 解析缓存由快照文件和追加日志组成，加载时回放日志，保存完整快照时清空日志
+// 文件带有格式版本、SDK 版本、账号和校验和组成的文件头，损坏的文件会被重命名隔离
+type FileStore struct {
+	dir       string
+	accountID string      // 绑定的账号，非空时拒绝加载其他账号写入的文件
+	keys      KeyProvider // 加密密钥，非空时加密写入并拒绝加载未加密的文件
+	logger    Logger
+	mutex     sync.Mutex
+}
+
+// NewFileStore 创建文件存储，目录不存在时自动创建
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := ensureCacheDir(dir); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// NewAccountFileStore 创建绑定账号的文件存储，加载时校验文件头中的账号
+func NewAccountFileStore(dir, accountID string, logger Logger) (*FileStore, error) {
+	store, err := NewFileStore(dir)
+	if err != nil {
+		return nil, err
+	}
+	store.accountID = accountID
+	store.logger = logger
+	return store, nil
+}
+
+// NewEncryptedFileStore 创建使用 AES-GCM 加密文件的存储
+// 无法解密（密钥错误或文件被篡改）和未加密的文件不会被加载，而是被隔离
+func NewEncryptedFileStore(dir, accountID string, keys KeyProvider, logger Logger) (*FileStore, error) {
+	store, err := NewAccountFileStore(dir, accountID, logger)
+	if err != nil {
+		return nil, err
+	}
+	store.keys = keys
+	return store, nil
+}
+
+// Dir 返回存储目录
+func (s *FileStore) Dir() string {
+	return s.dir
+}
+
+// LoadResolveCache 加载解析缓存（快照与日志合并后的结果）
+func (s *FileStore) LoadResolveCache() (*ResolveCacheData, error) {
+	var data *ResolveCacheData
+	var legacy bool
+	err := s.withFileLock(false, func() (err error) {
+		data, legacy, err = s.loadResolveCache()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if legacy {
+		if err := s.migrateFile(resolveCacheFile, &ResolveCacheData{}); err != nil && s.logger != nil {
+			s.logger.Printf("Failed to migrate %s: %v", resolveCacheFile, err)
+		}
+	}
+	return data, nil
+}
+
+// SaveResolveCache 保存解析缓存快照并清空日志，与文件中其他进程写入的记录合并（查询时间较新者优先）
+// 已有文件无法读取（版本不支持、缺少密钥或损坏后隔离失败）时不覆盖，返回错误
+func (s *FileStore) SaveResolveCache(data *ResolveCacheData) error {
+	return s.withFileLock(true, func() error {
+		existing, err := s.loadResolveCacheForSave()
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			data = mergeResolveCacheData(existing, data)
+		}
+		if err := s.writeJSONFile(resolveCacheFile, data); err != nil {
+			return err
+		}
+		return s.removeFile(resolveJournalFile)
+	})
+}
+
+// AppendResolveCache 追加解析缓存日志
+func (s *FileStore) AppendResolveCache(records []JournalRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	for _, record := range records {
+		line, err := s.encodeJournalLine(record)
+		if err != nil {
+			return err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	return s.withFileLock(true, func() error {
+		f, err := os.OpenFile(filepath.Join(s.dir, resolveJournalFile), os.O_CREATE|os.O_RDWR, 0600)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		// 上次写入中断时日志末尾可能是不完整的行，先截断，避免残缺行留在日志中间
+		size, err := truncateTornLine(f)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Seek(size, io.SeekStart); err != nil {
+			return err
+		}
+
+		// 新建的日志先写入文件头
+		if size == 0 {
+			header, err := s.newJournalHeader()
+			if err != nil {
+				return err
+			}
+			line, err := json.Marshal(&persistentFile{Header: header})
+			if err != nil {
+				return err
+			}
+			if _, err := f.Write(append(line, '\n')); err != nil {
+				return err
+			}
+		}
+
+		_, err = f.Write(buf.Bytes())
+		return err
+	})
+}
+
+// truncateTornLine 截断文件末尾没有换行的残缺行，返回截断后的文件大小
+func truncateTornLine(f *os.File) (int64, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	size := info.Size()
+	chunk := make([]byte, 4096)
+	for end := size; end > 0; {
+		start := end - int64(len(chunk))
+		if start < 0 {
+			start = 0
+		}
+		n, err := f.ReadAt(chunk[:end-start], start)
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+		if i := bytes.LastIndexByte(chunk[:n], '\n'); i >= 0 {
+			if keep := start + int64(i) + 1; keep < size {
+				return keep, f.Truncate(keep)
+			}
+			return size, nil
+		}
+		end = start
+	}
+	if size == 0 {
+		return 0, nil
+	}
+	return 0, f.Truncate(0)
+}
+
+// DeleteResolveCache 删除解析缓存快照和日志
+func (s *FileStore) DeleteResolveCache() error {
+	return s.withFileLock(true, func() error {
+		if err := s.removeFile(resolveCacheFile); err != nil {
+			return err
+		}
+		return s.removeFile(resolveJournalFile)
+	})
+}
+
+// loadResolveCache 读取快照并回放日志，均不存在时返回 nil，同时返回快照是否为旧格式（调用方需持有文件锁）
+func (s *FileStore) loadResolveCache() (*ResolveCacheData, bool, error) {
+	var snapshot ResolveCacheData
+	ok, legacy, err := s.readJSONFile(resolveCacheFile, &snapshot)
+	if err != nil {
+		return nil, false, err
+	}
+
+	journal, err := s.readJournal()
+	if err != nil {
+		return nil, false, err
+	}
+
+	switch {
+	case journal == nil && !ok:
+		return nil, false, nil
+	case journal == nil:
+		return &snapshot, legacy, nil
+	case !ok:
+		return journal, false, nil
+	}
+	return mergeResolveCacheData(&snapshot, journal), legacy, nil
+}
+
+// loadResolveCacheForSave 读取已有数据用于合并，损坏的快照或日志被隔离后重新读取（调用方需持有文件锁）
+// 只有文件不存在或已被隔离时才允许覆盖，其他无法读取的文件返回错误，避免丢失其他进程的数据
+func (s *FileStore) loadResolveCacheForSave() (*ResolveCacheData, error) {
+	// 快照和日志各可能被隔离一次
+	for retries := 2; ; retries-- {
+		existing, _, err := s.loadResolveCache()
+		if err == nil || !errors.Is(err, ErrCorruptCache) || retries == 0 {
+			return existing, err
+		}
+		if s.logger != nil {
+			s.logger.Printf("Discarding corrupted persistent cache before save: %v", err)
+		}
+	}
+}
+
+// readJournal 回放日志，不存在时返回 nil（调用方需持有文件锁）
+// 末尾的残缺行（进程崩溃导致的截断）会被跳过，其他无法解析或校验失败的行视为文件损坏
+func (s *FileStore) readJournal() (*ResolveCacheData, error) {
+	f, err := os.Open(filepath.Join(s.dir, resolveJournalFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	data := &ResolveCacheData{
+		Records:    make(map[string]*CacheEntry),
+		Stats:      make(map[string]*AccessStats),
+		Tombstones: make(map[string]time.Time),
+	}
+
+	reader := bufio.NewReader(f)
+	checked := false
+	for first := true; ; first = false {
+		line, readErr := reader.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			return nil, readErr
+		}
+		// 所有行都以换行结尾，末尾没有换行的是写入中断留下的残缺行
+		torn := readErr == io.EOF && len(line) > 0
+
+		if first {
+			// 校验文件头，文件头无法识别或被篡改时隔离整个日志
+			var file persistentFile
+			if json.Unmarshal(line, &file) == nil && file.Header != nil {
+				if checked, err = s.checkJournalHeader(file.Header); err != nil {
+					f.Close()
+					return nil, s.quarantineOnCorrupt(resolveJournalFile, err)
+				}
+				line = nil
+			} else if s.keys != nil && !torn {
+				f.Close()
+				return nil, s.quarantineOnCorrupt(resolveJournalFile, fmt.Errorf("%w: unencrypted journal", ErrCorruptCache))
+			}
+		}
+		if len(bytes.TrimSpace(line)) > 0 {
+			record, err := s.decodeJournalLine(line, checked)
+			switch {
+			case err == nil:
+				applyJournalRecord(data, record)
+			case torn:
+				if s.logger != nil {
+					s.logger.Printf("Ignoring incomplete last line of %s: %v", resolveJournalFile, err)
+				}
+			default:
+				f.Close()
+				return nil, s.quarantineOnCorrupt(resolveJournalFile, err)
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+	}
+	return data, nil
+}
+
+// applyJournalRecord 将单条日志应用到数据上，同一域名保留时间较新的记录或删除标记，访问统计取较大值
+func applyJournalRecord(data *ResolveCacheData, record JournalRecord) {
+	if record.Entry == nil {
+		if entry, ok := data.Records[record.Domain]; ok && !entry.writtenAt().After(record.Time) {
+			delete(data.Records, record.Domain)
+		}
+		if stats, ok := data.Stats[record.Domain]; ok && !stats.LastAccess.After(record.Time) {
+			delete(data.Stats, record.Domain)
+		}
+		if record.Time.After(data.Tombstones[record.Domain]) {
+			data.Tombstones[record.Domain] = record.Time
+		}
+		return
+	}
+
+	if record.Stats != nil {
+		applyJournalStats(data, record.Domain, record.Stats)
+	}
+
+	if existing, ok := data.Records[record.Domain]; ok && !record.Entry.QueryTime.After(existing.QueryTime) {
+		return
+	}
+	if deletedAt, ok := data.Tombstones[record.Domain]; ok && !record.Entry.writtenAt().After(deletedAt) {
+		return
+	}
+	data.Records[record.Domain] = record.Entry
+	delete(data.Tombstones, record.Domain)
+}
+
+// applyJournalStats 合并日志中的访问统计，早于删除标记的统计被丢弃
+func applyJournalStats(data *ResolveCacheData, domain string, stats *AccessStats) {
+	if deletedAt, ok := data.Tombstones[domain]; ok && !stats.LastAccess.After(deletedAt) {
+		return
+	}
+	existing, ok := data.Stats[domain]
+	if !ok {
+		data.Stats[domain] = &AccessStats{Hits: stats.Hits, LastAccess: stats.LastAccess}
+		return
+	}
+	if stats.Hits > existing.Hits {
+		existing.Hits = stats.Hits
+	}
+	if stats.LastAccess.After(existing.LastAccess) {
+		existing.LastAccess = stats.LastAccess
+	}
+}
+
+// LoadServiceIPs 加载服务IP缓存
+func (s *FileStore) LoadServiceIPs() (*ServiceIPCacheData, error) {
+	var data ServiceIPCacheData
+	var ok, legacy bool
+	err := s.withFileLock(false, func() (err error) {
+		ok, legacy, err = s.readJSONFile(serviceIPsFile, &data)
+		return err
+	})
+	if !ok || err != nil {
+		return nil, err
+	}
+	if legacy {
+		if err := s.migrateFile(serviceIPsFile, &ServiceIPCacheData{}); err != nil && s.logger != nil {
+			s.logger.Printf("Failed to migrate %s: %v", serviceIPsFile, err)
+		}
+	}
+	return &data, nil
+}
+
+// SaveServiceIPs 保存服务IP缓存，文件中已有更新的数据或已有文件无法读取时不覆盖
+func (s *FileStore) SaveServiceIPs(data *ServiceIPCacheData) error {
+	return s.withFileLock(true, func() error {
+		var existing ServiceIPCacheData
+		ok, _, err := s.readJSONFile(serviceIPsFile, &existing)
+		if errors.Is(err, ErrCorruptCache) {
+			// 损坏的文件已被隔离，重新读取确认
+			ok, _, err = s.readJSONFile(serviceIPsFile, &existing)
+		}
+		if err != nil {
+			return err
+		}
+		if ok && existing.UpdatedAt.After(data.UpdatedAt) {
+			return nil
+		}
+		return s.writeJSONFile(serviceIPsFile, data)
+	})
+}
+
+// DeleteServiceIPs 删除服务IP缓存
+func (s *FileStore) DeleteServiceIPs() error {
+	return s.withFileLock(true, func() error {
+		return s.removeFile(serviceIPsFile)
+	})
+}
+
+// withFileLock 在进程内互斥和跨进程文件锁的保护下执行 fn
+func (s *FileStore) withFileLock(exclusive bool, fn func() error) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	f, err := os.OpenFile(filepath.Join(s.dir, lockFileName), os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := lockFile(f, exclusive); err != nil {
+		return fmt.Errorf("failed to lock cache directory: %w", err)
+	}
+	defer unlockFile(f)
+
+	return fn()
+}
+
+// readJSONFile 读取并解析JSON文件，文件不存在时返回 false（调用方需持有文件锁）
+// legacy 表示文件为不带文件头的旧格式；损坏的文件被隔离并返回 ErrCorruptCache
+func (s *FileStore) readJSONFile(filename string, v interface{}) (ok bool, legacy bool, err error) {
+	data, err := os.ReadFile(filepath.Join(s.dir, filename))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, false, nil // 文件不存在不是错误
+		}
+		return false, false, err
+	}
+
+	legacy, err = s.decodeFile(filename, data, v)
+	if err != nil {
+		return false, false, s.quarantineOnCorrupt(filename, err)
+	}
+	return true, legacy, nil
+}
+
+// quarantineOnCorrupt 文件损坏时将其隔离，并在错误中附带隔离后的路径（调用方需持有文件锁）
+func (s *FileStore) quarantineOnCorrupt(filename string, err error) error {
+	if !errors.Is(err, ErrCorruptCache) {
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+	quarantined, qErr := s.quarantine(filename)
+	if qErr != nil {
+		return fmt.Errorf("%s: %w (quarantine failed: %v)", filename, err, qErr)
+	}
+	return fmt.Errorf("%s: %w (moved to %s)", filename, err, quarantined)
+}
+
+// writeJSONFile 原子性写入JSON文件（调用方需持有文件锁）
+func (s *FileStore) writeJSONFile(filename string, data interface{}) error {
+	filePath := filepath.Join(s.dir, filename)
+
+	// 序列化为带文件头的紧凑JSON
+	jsonData, err := s.encodeFile(filename, data)
+	if err != nil {
+		return err
+	}
+
+	// Windows：直接覆盖写入
+	if runtime.GOOS == "windows" {
+		return os.WriteFile(filePath, jsonData, 0600)
+	}
+
+	// 非 Windows：使用临时文件 + 原子重命名
+	tempPath := filePath + ".tmp"
+
+	// 写入临时文件
+	if err := os.WriteFile(tempPath, jsonData, 0600); err != nil {
+		return err
+	}
+
+	// 原子性重命名
+	return os.Rename(tempPath, filePath)
+}
+
+// removeFile 删除文件，文件不存在不是错误（调用方需持有文件锁）
+func (s *FileStore) removeFile(filename string) error {
+	if err := os.Remove(filepath.Join(s.dir, filename)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// MemoryStore 内存持久化存储，适用于测试或无可写文件系统的环境
+type MemoryStore struct {
+	mutex        sync.Mutex
+	resolveCache []byte
+	serviceIPs   []byte
+}
+
+// NewMemoryStore 创建内存存储
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// LoadResolveCache 加载解析缓存
+func (s *MemoryStore) LoadResolveCache() (*ResolveCacheData, error) {
+	var data ResolveCacheData
+	if ok, err := s.load(&s.resolveCache, &data); !ok || err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// SaveResolveCache 保存解析缓存
+func (s *MemoryStore) SaveResolveCache(data *ResolveCacheData) error {
+	return s.save(&s.resolveCache, data)
+}
+
+// DeleteResolveCache 删除解析缓存
+func (s *MemoryStore) DeleteResolveCache() error {
+	return s.save(&s.resolveCache, nil)
+}
+
+// LoadServiceIPs 加载服务IP缓存
+func (s *MemoryStore) LoadServiceIPs() (*ServiceIPCacheData, error) {
+	var data ServiceIPCacheData
+	if ok, err := s.load(&s.serviceIPs, &data); !ok || err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// SaveServiceIPs 保存服务IP缓存
+func (s *MemoryStore) SaveServiceIPs(data *ServiceIPCacheData) error {
+	return s.save(&s.serviceIPs, data)
+}
+
+// DeleteServiceIPs 删除服务IP缓存
+func (s *MemoryStore) DeleteServiceIPs() error {
+	return s.save(&s.serviceIPs, nil)
+}
+
+// load 反序列化已保存的数据（以序列化形式保存，避免调用方修改共享数据）
+func (s *MemoryStore) load(slot *[]byte, v interface{}) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if *slot == nil {
+		return false, nil
+	}
+	return true, json.Unmarshal(*slot, v)
+}
+
+// save 序列化保存数据，data 为 nil 时删除
+func (s *MemoryStore) save(slot *[]byte, data interface{}) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if data == nil {
+		*slot = nil
+		return nil
+	}
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	*slot = encoded
+	return nil
+}
@@ -0,0 +1,307 @@
+package httpdns
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PersistentFormatVersion 持久化文件格式版本
+// 版本 0 为不带文件头的旧格式，加载时自动迁移；高于当前版本的文件（如回滚后遇到新版本写入的文件）不加载
+const PersistentFormatVersion = 1
+
+// persistentFileHeader 持久化文件头
+type persistentFileHeader struct {
+	FormatVersion int    `json:"format_version"`
+	SDKVersion    string `json:"sdk_version"`
+	AccountID     string `json:"account_id,omitempty"`
+	Encryption    string `json:"encryption,omitempty"` // 加密算法，为空表示未加密
+	Checksum      string `json:"checksum,omitempty"`   // 快照为 data 的 SHA-256，日志为文件头自身（不含该字段）的 SHA-256
+}
+
+// persistentFile 带文件头的持久化文件
+type persistentFile struct {
+	Header *persistentFileHeader `json:"header"`
+	Data   json.RawMessage       `json:"data,omitempty"`
+}
+
+// sealedJournalLine 加密的日志记录
+type sealedJournalLine struct {
+	Sealed []byte `json:"sealed"`
+}
+
+// checkedJournalLine 带 CRC32 校验的明文日志记录
+type checkedJournalLine struct {
+	Record json.RawMessage `json:"record"`
+	CRC    uint32          `json:"crc"`
+}
+
+// newFileHeader 创建当前版本的文件头，加密算法按当前密钥长度记录
+func (s *FileStore) newFileHeader() (*persistentFileHeader, error) {
+	header := &persistentFileHeader{
+		FormatVersion: PersistentFormatVersion,
+		SDKVersion:    SDKVersion,
+		AccountID:     s.accountID,
+	}
+	if s.keys != nil {
+		key, err := s.keys.Key()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrCacheKeyUnavailable, err)
+		}
+		header.Encryption = cacheEncryptionAlgorithm(key)
+	}
+	return header, nil
+}
+
+// encodeFile 序列化数据并添加文件头，配置了密钥时加密数据
+func (s *FileStore) encodeFile(filename string, data interface{}) ([]byte, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.keys != nil {
+		sealed, err := sealCacheData(s.keys, raw, s.additionalData(filename))
+		if err != nil {
+			return nil, err
+		}
+		if raw, err = json.Marshal(sealed); err != nil {
+			return nil, err
+		}
+	}
+
+	header, err := s.newFileHeader()
+	if err != nil {
+		return nil, err
+	}
+	header.Checksum = checksumOf(raw)
+	return json.Marshal(&persistentFile{Header: header, Data: raw})
+}
+
+// decodeFile 校验文件头并解析数据，返回是否为需要迁移的旧格式
+// 文件损坏、校验和不匹配、账号不匹配、解密失败或配置了密钥但文件未加密时返回 ErrCorruptCache
+func (s *FileStore) decodeFile(filename string, content []byte, v interface{}) (bool, error) {
+	var file persistentFile
+	if err := json.Unmarshal(content, &file); err != nil {
+		return false, fmt.Errorf("%w: %v", ErrCorruptCache, err)
+	}
+
+	// 旧格式：整个文件即为数据
+	if file.Header == nil {
+		if s.keys != nil {
+			return false, fmt.Errorf("%w: unencrypted file", ErrCorruptCache)
+		}
+		if err := json.Unmarshal(content, v); err != nil {
+			return false, fmt.Errorf("%w: %v", ErrCorruptCache, err)
+		}
+		return true, nil
+	}
+
+	if err := s.checkHeader(file.Header); err != nil {
+		return false, err
+	}
+	if checksumOf(file.Data) != file.Header.Checksum {
+		return false, fmt.Errorf("%w: checksum mismatch", ErrCorruptCache)
+	}
+
+	raw := []byte(file.Data)
+	if s.keys != nil {
+		var sealed []byte
+		if err := json.Unmarshal(file.Data, &sealed); err != nil {
+			return false, fmt.Errorf("%w: %v", ErrCorruptCache, err)
+		}
+		plaintext, err := openCacheData(s.keys, sealed, s.additionalData(filename))
+		if err != nil {
+			return false, err
+		}
+		raw = plaintext
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return false, fmt.Errorf("%w: %v", ErrCorruptCache, err)
+	}
+	return false, nil
+}
+
+// newJournalHeader 创建日志文件头，校验和覆盖文件头自身
+func (s *FileStore) newJournalHeader() (*persistentFileHeader, error) {
+	header, err := s.newFileHeader()
+	if err != nil {
+		return nil, err
+	}
+	sum, err := journalHeaderChecksum(header)
+	if err != nil {
+		return nil, err
+	}
+	header.Checksum = sum
+	return header, nil
+}
+
+// checkJournalHeader 校验日志文件头，返回日志记录是否带校验
+// 旧版本写入的日志文件头没有校验和，其中的明文记录也没有校验
+func (s *FileStore) checkJournalHeader(header *persistentFileHeader) (bool, error) {
+	if err := s.checkHeader(header); err != nil {
+		return false, err
+	}
+	if header.Checksum == "" {
+		return false, nil
+	}
+	sum, err := journalHeaderChecksum(header)
+	if err != nil || sum != header.Checksum {
+		return false, fmt.Errorf("%w: journal header checksum mismatch", ErrCorruptCache)
+	}
+	return true, nil
+}
+
+// journalHeaderChecksum 计算日志文件头（不含校验和字段）的 SHA-256
+func journalHeaderChecksum(header *persistentFileHeader) (string, error) {
+	unsigned := *header
+	unsigned.Checksum = ""
+	raw, err := json.Marshal(&unsigned)
+	if err != nil {
+		return "", err
+	}
+	return checksumOf(raw), nil
+}
+
+// encodeJournalLine 序列化单条日志记录（不含换行），配置了密钥时加密，否则附带 CRC32
+func (s *FileStore) encodeJournalLine(record JournalRecord) ([]byte, error) {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	if s.keys == nil {
+		return json.Marshal(&checkedJournalLine{Record: line, CRC: crc32.ChecksumIEEE(line)})
+	}
+
+	sealed, err := sealCacheData(s.keys, line, s.additionalData(resolveJournalFile))
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(&sealedJournalLine{Sealed: sealed})
+}
+
+// decodeJournalLine 解析单条日志记录，checked 表示明文记录必须带 CRC32
+// 无法解析、校验失败或解密失败的行返回 ErrCorruptCache
+func (s *FileStore) decodeJournalLine(line []byte, checked bool) (JournalRecord, error) {
+	var record JournalRecord
+	raw := line
+	if s.keys == nil {
+		var wrapped checkedJournalLine
+		if err := json.Unmarshal(line, &wrapped); err != nil {
+			return record, fmt.Errorf("%w: %v", ErrCorruptCache, err)
+		}
+		switch {
+		case wrapped.Record != nil:
+			if crc32.ChecksumIEEE(wrapped.Record) != wrapped.CRC {
+				return record, fmt.Errorf("%w: journal record checksum mismatch", ErrCorruptCache)
+			}
+			raw = wrapped.Record
+		case checked:
+			return record, fmt.Errorf("%w: journal record without checksum", ErrCorruptCache)
+		}
+	} else {
+		var sealed sealedJournalLine
+		if err := json.Unmarshal(line, &sealed); err != nil {
+			return record, fmt.Errorf("%w: %v", ErrCorruptCache, err)
+		}
+		plaintext, err := openCacheData(s.keys, sealed.Sealed, s.additionalData(resolveJournalFile))
+		if err != nil {
+			return record, err
+		}
+		raw = plaintext
+	}
+
+	if err := json.Unmarshal(raw, &record); err != nil || record.Domain == "" {
+		return record, fmt.Errorf("%w: invalid journal record", ErrCorruptCache)
+	}
+	return record, nil
+}
+
+// additionalData 加密的附加认证数据，将密文绑定到文件名和账号，防止文件被替换
+func (s *FileStore) additionalData(filename string) []byte {
+	return []byte(filename + "\x00" + s.accountID)
+}
+
+// checkHeader 校验文件头的格式版本、账号和加密方式
+func (s *FileStore) checkHeader(header *persistentFileHeader) error {
+	if header.FormatVersion <= 0 || header.FormatVersion > PersistentFormatVersion {
+		return fmt.Errorf("%w: version %d written by SDK %s", ErrUnsupportedCache, header.FormatVersion, header.SDKVersion)
+	}
+	if header.AccountID != "" && s.accountID != "" && header.AccountID != s.accountID {
+		return fmt.Errorf("%w: written for account %s", ErrCorruptCache, header.AccountID)
+	}
+
+	switch {
+	case header.Encryption == "" && s.keys != nil:
+		return fmt.Errorf("%w: unencrypted file", ErrCorruptCache)
+	case header.Encryption != "" && s.keys == nil:
+		return fmt.Errorf("%w: file is encrypted with %s", ErrCacheKeyUnavailable, header.Encryption)
+	case header.Encryption != "" && !isCacheEncryptionAlgorithm(header.Encryption):
+		return fmt.Errorf("%w: encryption %s", ErrUnsupportedCache, header.Encryption)
+	}
+	return nil
+}
+
+// quarantine 将损坏的文件移到一旁保留现场，返回新的文件路径（调用方需持有文件锁）
+func (s *FileStore) quarantine(filename string) (string, error) {
+	path := filepath.Join(s.dir, filename)
+	quarantined := fmt.Sprintf("%s.corrupt-%d", path, time.Now().UnixNano())
+	if err := os.Rename(path, quarantined); err != nil {
+		return "", err
+	}
+	s.pruneQuarantined(path)
+	return quarantined, nil
+}
+
+// maxQuarantinedFiles 每个持久化文件最多保留的隔离文件数
+const maxQuarantinedFiles = 3
+
+// pruneQuarantined 删除 path 较早的隔离文件，只保留最新的 maxQuarantinedFiles 个
+func (s *FileStore) pruneQuarantined(path string) {
+	matches, err := filepath.Glob(path + ".corrupt-*")
+	if err != nil || len(matches) <= maxQuarantinedFiles {
+		return
+	}
+
+	// 按文件名中的纳秒时间戳排序，时间戳无法解析的文件视为最早
+	stamp := func(name string) int64 {
+		n, _ := strconv.ParseInt(strings.TrimPrefix(name, path+".corrupt-"), 10, 64)
+		return n
+	}
+	sort.Slice(matches, func(i, j int) bool { return stamp(matches[i]) < stamp(matches[j]) })
+
+	for _, name := range matches[:len(matches)-maxQuarantinedFiles] {
+		if err := os.Remove(name); err != nil && s.logger != nil {
+			s.logger.Printf("Failed to remove quarantined cache file %s: %v", name, err)
+		}
+	}
+}
+
+// migrateFile 将旧格式文件重写为当前格式
+func (s *FileStore) migrateFile(filename string, v interface{}) error {
+	return s.withFileLock(true, func() error {
+		// 加锁后重新读取，其他进程可能已完成迁移
+		ok, legacy, err := s.readJSONFile(filename, v)
+		if !ok || !legacy || err != nil {
+			return err
+		}
+		if s.logger != nil {
+			s.logger.Printf("Migrating %s to persistent format version %d", filename, PersistentFormatVersion)
+		}
+		return s.writeJSONFile(filename, v)
+	})
+}
+
+// checksumOf 计算数据的 SHA-256
+func checksumOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
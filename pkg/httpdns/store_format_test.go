@@ -0,0 +1,326 @@
+package httpdns
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileStore_MigrateLegacyFormat(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewAccountFileStore(dir, "test123", nil)
+	if err != nil {
+		t.Fatalf("NewAccountFileStore() error = %v", err)
+	}
+
+	// 旧版本写入的无文件头数据
+	legacy := `{"records":{"example.com":{"ipv4":["1.2.3.4"],"ttl":60,"query_time":"` + time.Now().Format(time.RFC3339Nano) + `"}}}`
+	if err := os.WriteFile(filepath.Join(dir, resolveCacheFile), []byte(legacy), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, serviceIPsFile), []byte(`{"ips":["203.107.1.1"],"updated_at":"2026-01-01T00:00:00Z"}`), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	data, err := store.LoadResolveCache()
+	if err != nil || data == nil || data.Records["example.com"] == nil {
+		t.Fatalf("LoadResolveCache() = %+v, %v, want legacy record", data, err)
+	}
+	ips, err := store.LoadServiceIPs()
+	if err != nil || ips == nil || len(ips.IPs) != 1 {
+		t.Fatalf("LoadServiceIPs() = %+v, %v, want legacy service IPs", ips, err)
+	}
+
+	// 加载后文件被重写为带文件头的当前格式
+	for _, name := range []string{resolveCacheFile, serviceIPsFile} {
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("ReadFile(%s) error = %v", name, err)
+		}
+		var file persistentFile
+		if err := json.Unmarshal(content, &file); err != nil || file.Header == nil {
+			t.Fatalf("%s not migrated: %s", name, content)
+		}
+		if file.Header.FormatVersion != PersistentFormatVersion || file.Header.SDKVersion != SDKVersion || file.Header.AccountID != "test123" {
+			t.Errorf("%s header = %+v", name, file.Header)
+		}
+	}
+
+	if data, err := store.LoadResolveCache(); err != nil || data.Records["example.com"] == nil {
+		t.Errorf("LoadResolveCache() after migration = %+v, %v", data, err)
+	}
+}
+
+func TestFileStore_Quarantine(t *testing.T) {
+	valid := func(t *testing.T, accountID string) []byte {
+		store := &FileStore{accountID: accountID}
+		content, err := store.encodeFile(resolveCacheFile, &ResolveCacheData{Records: map[string]*CacheEntry{
+			"example.com": {IPv4: []string{"1.2.3.4"}, TTL: 60, QueryTime: time.Now()},
+		}})
+		if err != nil {
+			t.Fatalf("encodeFile() error = %v", err)
+		}
+		return content
+	}
+
+	tests := []struct {
+		name           string
+		content        func(t *testing.T) []byte
+		wantErr        error
+		wantQuarantine bool
+	}{
+		{
+			name:           "not json",
+			content:        func(t *testing.T) []byte { return []byte("{corrupt") },
+			wantErr:        ErrCorruptCache,
+			wantQuarantine: true,
+		},
+		{
+			name: "checksum mismatch",
+			content: func(t *testing.T) []byte {
+				return []byte(strings.Replace(string(valid(t, "test123")), "1.2.3.4", "6.6.6.6", 1))
+			},
+			wantErr:        ErrCorruptCache,
+			wantQuarantine: true,
+		},
+		{
+			name:           "account mismatch",
+			content:        func(t *testing.T) []byte { return valid(t, "other") },
+			wantErr:        ErrCorruptCache,
+			wantQuarantine: true,
+		},
+		{
+			name: "future version",
+			content: func(t *testing.T) []byte {
+				return []byte(`{"header":{"format_version":99,"sdk_version":"9.0.0"},"data":{}}`)
+			},
+			wantErr:        ErrUnsupportedCache,
+			wantQuarantine: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			store, err := NewAccountFileStore(dir, "test123", nil)
+			if err != nil {
+				t.Fatalf("NewAccountFileStore() error = %v", err)
+			}
+			path := filepath.Join(dir, resolveCacheFile)
+			if err := os.WriteFile(path, tt.content(t), 0600); err != nil {
+				t.Fatalf("WriteFile() error = %v", err)
+			}
+
+			if _, err := store.LoadResolveCache(); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("LoadResolveCache() error = %v, want %v", err, tt.wantErr)
+			}
+
+			quarantined, _ := filepath.Glob(path + ".corrupt-*")
+			if got := len(quarantined) == 1; got != tt.wantQuarantine {
+				t.Errorf("quarantined files = %v, want quarantine %v", quarantined, tt.wantQuarantine)
+			}
+			if _, err := os.Stat(path); os.IsNotExist(err) == !tt.wantQuarantine {
+				t.Errorf("original file exists = %v, want %v", err == nil, !tt.wantQuarantine)
+			}
+		})
+	}
+}
+
+func TestFileStore_QuarantinePruned(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewAccountFileStore(dir, "test123", nil)
+	if err != nil {
+		t.Fatalf("NewAccountFileStore() error = %v", err)
+	}
+	path := filepath.Join(dir, resolveCacheFile)
+
+	// 反复损坏的文件只保留最新的 maxQuarantinedFiles 个隔离文件
+	var last string
+	for i := 0; i < maxQuarantinedFiles+2; i++ {
+		if err := os.WriteFile(path, []byte("{corrupt"), 0600); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+		if last, err = store.quarantine(resolveCacheFile); err != nil {
+			t.Fatalf("quarantine() error = %v", err)
+		}
+	}
+
+	quarantined, _ := filepath.Glob(path + ".corrupt-*")
+	if len(quarantined) != maxQuarantinedFiles {
+		t.Errorf("quarantined files = %v, want %d", quarantined, maxQuarantinedFiles)
+	}
+	if _, err := os.Stat(last); err != nil {
+		t.Errorf("newest quarantined file %s should be kept: %v", last, err)
+	}
+}
+
+func TestFileStore_SaveKeepsUnreadableFiles(t *testing.T) {
+	record := &ResolveCacheData{Records: map[string]*CacheEntry{
+		"example.com": {IPv4: []string{"1.2.3.4"}, TTL: 60, QueryTime: time.Now()},
+	}}
+
+	tests := []struct {
+		name    string
+		content func(t *testing.T, dir string) []byte
+		wantErr error
+	}{
+		{
+			name: "future version",
+			content: func(t *testing.T, dir string) []byte {
+				return []byte(`{"header":{"format_version":99,"sdk_version":"9.0.0"},"data":{}}`)
+			},
+			wantErr: ErrUnsupportedCache,
+		},
+		{
+			name: "encrypted without key",
+			content: func(t *testing.T, dir string) []byte {
+				if err := newEncryptedTestStore(t, dir, 1).SaveResolveCache(record); err != nil {
+					t.Fatalf("SaveResolveCache() error = %v", err)
+				}
+				content, _ := os.ReadFile(filepath.Join(dir, resolveCacheFile))
+				return content
+			},
+			wantErr: ErrCacheKeyUnavailable,
+		},
+		{
+			name:    "corrupt",
+			content: func(t *testing.T, dir string) []byte { return []byte("{corrupt") },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, resolveCacheFile)
+			content := tt.content(t, dir)
+			if err := os.WriteFile(path, content, 0600); err != nil {
+				t.Fatalf("WriteFile() error = %v", err)
+			}
+
+			store, _ := NewAccountFileStore(dir, "test123", nil)
+			err := store.SaveResolveCache(record)
+			if tt.wantErr == nil {
+				// 损坏的文件被隔离后正常保存
+				if err != nil {
+					t.Fatalf("SaveResolveCache() error = %v", err)
+				}
+				if quarantined, _ := filepath.Glob(path + ".corrupt-*"); len(quarantined) != 1 {
+					t.Errorf("quarantined files = %v, want 1", quarantined)
+				}
+				if data, err := store.LoadResolveCache(); err != nil || data.Records["example.com"] == nil {
+					t.Errorf("LoadResolveCache() = %+v, %v, want saved record", data, err)
+				}
+				return
+			}
+
+			// 无法读取的文件保持不变
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SaveResolveCache() error = %v, want %v", err, tt.wantErr)
+			}
+			if got, _ := os.ReadFile(path); string(got) != string(content) {
+				t.Errorf("file overwritten: %s", got)
+			}
+		})
+	}
+}
+
+func TestFileStore_JournalChecksum(t *testing.T) {
+	now := time.Now()
+	appendRecords := func(t *testing.T, store *FileStore) {
+		t.Helper()
+		if err := store.AppendResolveCache([]JournalRecord{
+			{Domain: "a.com", Entry: &CacheEntry{IPv4: []string{"1.2.3.4"}, TTL: 60, QueryTime: now}, Time: now},
+			{Domain: "b.com", Entry: &CacheEntry{IPv4: []string{"5.6.7.8"}, TTL: 60, QueryTime: now}, Time: now},
+		}); err != nil {
+			t.Fatalf("AppendResolveCache() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		tamper  func(content string) string
+		wantErr error
+	}{
+		{
+			name:   "record tampered",
+			tamper: func(content string) string { return strings.Replace(content, "1.2.3.4", "6.6.6.6", 1) },
+		},
+		{
+			name:   "header tampered",
+			tamper: func(content string) string { return strings.Replace(content, `"sdk_version":"`, `"sdk_version":"x`, 1) },
+		},
+		{
+			name: "checksum removed",
+			tamper: func(content string) string {
+				lines := strings.SplitN(content, "\n", 3)
+				var line checkedJournalLine
+				json.Unmarshal([]byte(lines[1]), &line)
+				lines[1] = string(line.Record)
+				return strings.Join(lines, "\n")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			store, _ := NewAccountFileStore(dir, "test123", nil)
+			appendRecords(t, store)
+
+			path := filepath.Join(dir, resolveJournalFile)
+			content, _ := os.ReadFile(path)
+			if err := os.WriteFile(path, []byte(tt.tamper(string(content))), 0600); err != nil {
+				t.Fatalf("WriteFile() error = %v", err)
+			}
+
+			if data, err := store.LoadResolveCache(); !errors.Is(err, ErrCorruptCache) {
+				t.Fatalf("LoadResolveCache() = %+v, %v, want ErrCorruptCache", data, err)
+			}
+			if quarantined, _ := filepath.Glob(path + ".corrupt-*"); len(quarantined) != 1 {
+				t.Errorf("quarantined files = %v, want 1", quarantined)
+			}
+		})
+	}
+
+	// 旧版本写入的日志（文件头没有校验和，记录没有 CRC）仍可加载
+	dir := t.TempDir()
+	header, _ := json.Marshal(&persistentFile{Header: &persistentFileHeader{FormatVersion: 1, SDKVersion: "1.0.1"}})
+	record, _ := json.Marshal(JournalRecord{Domain: "a.com", Entry: &CacheEntry{IPv4: []string{"1.2.3.4"}, TTL: 60, QueryTime: now}, Time: now})
+	if err := os.WriteFile(filepath.Join(dir, resolveJournalFile), []byte(string(header)+"\n"+string(record)+"\n"), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	store, _ := NewFileStore(dir)
+	if data, err := store.LoadResolveCache(); err != nil || data.Records["a.com"] == nil {
+		t.Errorf("LoadResolveCache() legacy journal = %+v, %v, want a.com", data, err)
+	}
+}
+
+func TestResolver_CorruptCacheMetrics(t *testing.T) {
+	config := DefaultConfig()
+	config.AccountID = "test123"
+	config.EnablePersistentCache = true
+	config.EnableMetrics = true
+	config.CacheDir = t.TempDir()
+
+	dir := filepath.Join(config.CacheDir, config.AccountID)
+	if err := ensureCacheDir(dir); err != nil {
+		t.Fatalf("ensureCacheDir() error = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, resolveCacheFile), []byte("{corrupt"), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	resolver := NewResolver(config)
+	if got := resolver.GetMetrics().CorruptCacheFiles; got != 1 {
+		t.Errorf("CorruptCacheFiles = %d, want 1", got)
+	}
+
+	// 隔离后重新启动不再报告损坏
+	if got := NewResolver(config).GetMetrics().CorruptCacheFiles; got != 0 {
+		t.Errorf("CorruptCacheFiles after quarantine = %d, want 0", got)
+	}
+}
@@ -0,0 +1,583 @@
+package httpdns
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testPersistentStore 对 PersistentStore 实现执行通用的读写删除检查
+func testPersistentStore(t *testing.T, store PersistentStore) {
+	t.Helper()
+
+	if data, err := store.LoadResolveCache(); err != nil || data != nil {
+		t.Fatalf("LoadResolveCache() on empty store = %v, %v, want nil, nil", data, err)
+	}
+	if data, err := store.LoadServiceIPs(); err != nil || data != nil {
+		t.Fatalf("LoadServiceIPs() on empty store = %v, %v, want nil, nil", data, err)
+	}
+
+	records := &ResolveCacheData{
+		Records: map[string]*CacheEntry{
+			"example.com": {IPv4: []string{"1.2.3.4"}, TTL: 60, QueryTime: time.Now()},
+		},
+	}
+	if err := store.SaveResolveCache(records); err != nil {
+		t.Fatalf("SaveResolveCache() error = %v", err)
+	}
+	if err := store.SaveServiceIPs(&ServiceIPCacheData{IPs: []string{"203.107.1.1"}, UpdatedAt: time.Now()}); err != nil {
+		t.Fatalf("SaveServiceIPs() error = %v", err)
+	}
+
+	// 修改已保存的数据不影响存储内容
+	records.Records["example.com"].IPv4[0] = "9.9.9.9"
+
+	loaded, err := store.LoadResolveCache()
+	if err != nil || loaded == nil {
+		t.Fatalf("LoadResolveCache() = %v, %v", loaded, err)
+	}
+	if entry := loaded.Records["example.com"]; entry == nil || entry.IPv4[0] != "1.2.3.4" {
+		t.Errorf("LoadResolveCache() record = %+v, want 1.2.3.4", entry)
+	}
+	ips, err := store.LoadServiceIPs()
+	if err != nil || ips == nil || len(ips.IPs) != 1 {
+		t.Errorf("LoadServiceIPs() = %+v, %v", ips, err)
+	}
+
+	if err := store.DeleteResolveCache(); err != nil {
+		t.Fatalf("DeleteResolveCache() error = %v", err)
+	}
+	if err := store.DeleteServiceIPs(); err != nil {
+		t.Fatalf("DeleteServiceIPs() error = %v", err)
+	}
+	if data, err := store.LoadResolveCache(); err != nil || data != nil {
+		t.Errorf("LoadResolveCache() after delete = %v, %v, want nil, nil", data, err)
+	}
+	if data, err := store.LoadServiceIPs(); err != nil || data != nil {
+		t.Errorf("LoadServiceIPs() after delete = %v, %v, want nil, nil", data, err)
+	}
+
+	// 重复删除不是错误
+	if err := store.DeleteResolveCache(); err != nil {
+		t.Errorf("DeleteResolveCache() twice error = %v", err)
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "nested", "test123")
+	store, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("NewFileStore() error = %v", err)
+	}
+	if store.Dir() != dir {
+		t.Errorf("Dir() = %s, want %s", store.Dir(), dir)
+	}
+
+	testPersistentStore(t, store)
+
+	// 损坏的文件返回错误
+	if err := os.WriteFile(filepath.Join(dir, resolveCacheFile), []byte("{corrupt"), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if _, err := store.LoadResolveCache(); err == nil {
+		t.Error("LoadResolveCache() with corrupt file should fail")
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testPersistentStore(t, NewMemoryStore())
+}
+
+func TestCacheManager_CustomStore(t *testing.T) {
+	store := NewMemoryStore()
+
+	config := DefaultConfig()
+	config.AccountID = "test123"
+	config.EnablePersistentCache = true
+	config.PersistentStore = store
+
+	cm := NewCacheManager(config)
+	cm.Set("example.com", &CacheEntry{IPv4: []string{"1.2.3.4"}, TTL: 60, QueryTime: time.Now()})
+	cm.doSaveResolveCache()
+
+	cm2 := NewCacheManager(config)
+	if err := cm2.LoadFromDisk(); err != nil {
+		t.Fatalf("LoadFromDisk() error = %v", err)
+	}
+	if _, hit, _ := cm2.Get("example.com"); !hit {
+		t.Error("Get() should hit after loading from custom store")
+	}
+
+	// 清空缓存后持久化数据只保留删除标记
+	cm2.Flush()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		data, err := store.LoadResolveCache()
+		if err == nil && data != nil && len(data.Records) == 0 && !data.Tombstones["example.com"].IsZero() {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("LoadResolveCache() after Flush = %+v, %v, want tombstone only", data, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCacheManager_CacheDir(t *testing.T) {
+	baseDir := t.TempDir()
+
+	config := DefaultConfig()
+	config.AccountID = "test123"
+	config.EnablePersistentCache = true
+	config.CacheDir = baseDir
+
+	cm := NewCacheManager(config)
+	if !cm.persistent {
+		t.Fatal("persistent cache should be enabled")
+	}
+	fileStore, ok := cm.store.(*FileStore)
+	if !ok {
+		t.Fatalf("store = %T, want *FileStore", cm.store)
+	}
+	if want := filepath.Join(baseDir, "test123"); fileStore.Dir() != want {
+		t.Errorf("Dir() = %s, want %s", fileStore.Dir(), want)
+	}
+
+	// 不可写的目录会禁用持久化缓存
+	blocker := filepath.Join(baseDir, "file")
+	if err := os.WriteFile(blocker, nil, 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	config.CacheDir = blocker
+	if cm := NewCacheManager(config); cm.persistent {
+		t.Error("persistent cache should be disabled when CacheDir is unusable")
+	}
+}
+
+func TestMergeResolveCacheData(t *testing.T) {
+	now := time.Now()
+	a := &ResolveCacheData{
+		Records: map[string]*CacheEntry{
+			"newer.com":   {IPv4: []string{"1.1.1.1"}, TTL: 60, QueryTime: now},
+			"older.com":   {IPv4: []string{"1.1.1.1"}, TTL: 60, QueryTime: now.Add(-time.Minute)},
+			"deleted.com": {IPv4: []string{"1.1.1.1"}, TTL: 60, QueryTime: now.Add(-time.Minute)},
+		},
+		Stats: map[string]*AccessStats{"newer.com": {Hits: 5, LastAccess: now.Add(-time.Minute)}},
+	}
+	b := &ResolveCacheData{
+		Records: map[string]*CacheEntry{
+			"newer.com":  {IPv4: []string{"2.2.2.2"}, TTL: 60, QueryTime: now.Add(-time.Minute)},
+			"older.com":  {IPv4: []string{"2.2.2.2"}, TTL: 60, QueryTime: now},
+			"only-b.com": {IPv4: []string{"2.2.2.2"}, TTL: 60, QueryTime: now},
+		},
+		Stats: map[string]*AccessStats{"newer.com": {Hits: 3, LastAccess: now}},
+		Tombstones: map[string]time.Time{
+			"deleted.com": now.Add(-time.Second),
+			"ancient.com": now.Add(-2 * tombstoneRetention),
+		},
+	}
+
+	merged := mergeResolveCacheData(a, b)
+
+	want := map[string]string{"newer.com": "1.1.1.1", "older.com": "2.2.2.2", "only-b.com": "2.2.2.2"}
+	if len(merged.Records) != len(want) {
+		t.Fatalf("merged records = %d, want %d", len(merged.Records), len(want))
+	}
+	for domain, ip := range want {
+		if entry := merged.Records[domain]; entry == nil || entry.IPv4[0] != ip {
+			t.Errorf("merged %s = %+v, want %s", domain, entry, ip)
+		}
+	}
+	if stats := merged.Stats["newer.com"]; stats.Hits != 5 || !stats.LastAccess.Equal(now) {
+		t.Errorf("merged stats = %+v, want max hits and latest access", stats)
+	}
+	if _, ok := merged.Tombstones["deleted.com"]; !ok {
+		t.Error("tombstone for deleted.com should be kept")
+	}
+	if _, ok := merged.Tombstones["ancient.com"]; ok {
+		t.Error("tombstone older than retention should be dropped")
+	}
+	if a.Stats["newer.com"].Hits != 5 || len(a.Records) != 3 {
+		t.Error("mergeResolveCacheData() should not modify its inputs")
+	}
+}
+
+func TestFileStore_MultiProcess(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+
+	// 两个独立的 FileStore 模拟同一目录下的两个进程
+	store1, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("NewFileStore() error = %v", err)
+	}
+	store2, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("NewFileStore() error = %v", err)
+	}
+
+	if err := store1.SaveResolveCache(&ResolveCacheData{Records: map[string]*CacheEntry{
+		"a.com":      {IPv4: []string{"1.1.1.1"}, TTL: 60, QueryTime: now},
+		"shared.com": {IPv4: []string{"1.1.1.1"}, TTL: 60, QueryTime: now},
+	}}); err != nil {
+		t.Fatalf("SaveResolveCache() error = %v", err)
+	}
+	if err := store2.SaveResolveCache(&ResolveCacheData{Records: map[string]*CacheEntry{
+		"b.com":      {IPv4: []string{"2.2.2.2"}, TTL: 60, QueryTime: now},
+		"shared.com": {IPv4: []string{"2.2.2.2"}, TTL: 60, QueryTime: now.Add(-time.Minute)},
+	}}); err != nil {
+		t.Fatalf("SaveResolveCache() error = %v", err)
+	}
+
+	data, err := store1.LoadResolveCache()
+	if err != nil || data == nil {
+		t.Fatalf("LoadResolveCache() = %v, %v", data, err)
+	}
+	if len(data.Records) != 3 {
+		t.Errorf("merged records = %d, want 3", len(data.Records))
+	}
+	if entry := data.Records["shared.com"]; entry.IPv4[0] != "1.1.1.1" {
+		t.Errorf("shared.com = %v, want newer record 1.1.1.1", entry.IPv4)
+	}
+
+	// 服务IP保留更新时间较新的数据
+	if err := store1.SaveServiceIPs(&ServiceIPCacheData{IPs: []string{"203.107.1.1"}, UpdatedAt: now}); err != nil {
+		t.Fatalf("SaveServiceIPs() error = %v", err)
+	}
+	if err := store2.SaveServiceIPs(&ServiceIPCacheData{IPs: []string{"203.107.1.2"}, UpdatedAt: now.Add(-time.Hour)}); err != nil {
+		t.Fatalf("SaveServiceIPs() error = %v", err)
+	}
+	if ips, err := store2.LoadServiceIPs(); err != nil || ips.IPs[0] != "203.107.1.1" {
+		t.Errorf("LoadServiceIPs() = %+v, %v, want newer 203.107.1.1", ips, err)
+	}
+}
+
+func TestCacheManager_SyncFromStore(t *testing.T) {
+	// 默认开启定时同步
+	if got := DefaultConfig().PersistentSyncInterval; got <= 0 {
+		t.Errorf("default PersistentSyncInterval = %v, want periodic sync enabled", got)
+	}
+
+	dir := t.TempDir()
+	newManager := func() *CacheManager {
+		return &CacheManager{
+			cache:      make(map[string]*CacheEntry),
+			enabled:    true,
+			persistent: true,
+			store:      &FileStore{dir: dir},
+		}
+	}
+
+	cm1 := newManager()
+	cm2 := newManager()
+
+	cm1.Set("a.com", &CacheEntry{IPv4: []string{"1.1.1.1"}, TTL: 60, QueryTime: time.Now()})
+	cm1.doSaveResolveCache()
+	cm2.Set("b.com", &CacheEntry{IPv4: []string{"2.2.2.2"}, TTL: 60, QueryTime: time.Now()})
+	cm2.doSaveResolveCache()
+
+	// 进程1 获取进程2 写入的记录
+	if got, err := cm1.SyncFromStore(); err != nil || got != 1 {
+		t.Fatalf("SyncFromStore() = %d, %v, want 1", got, err)
+	}
+	if _, hit, _ := cm1.Get("b.com"); !hit {
+		t.Error("cm1 should see b.com written by cm2")
+	}
+
+	// 进程2 删除的记录不会被进程1 的旧记录写回
+	cm2.SyncFromStore()
+	cm2.Delete("a.com")
+	cm2.doSaveResolveCache()
+	cm1.doSaveResolveCache()
+
+	data, err := cm1.store.LoadResolveCache()
+	if err != nil || data == nil {
+		t.Fatalf("LoadResolveCache() = %v, %v", data, err)
+	}
+	if _, ok := data.Records["a.com"]; ok {
+		t.Error("deleted a.com should not be resurrected by a stale peer")
+	}
+	if _, err := cm1.SyncFromStore(); err != nil {
+		t.Fatalf("SyncFromStore() error = %v", err)
+	}
+	if _, ok := cm1.GetStale("a.com", -1); ok {
+		t.Error("cm1 should drop a.com after syncing the peer's deletion")
+	}
+
+	// 重新解析后的新记录可以覆盖删除标记
+	cm1.Set("a.com", &CacheEntry{IPv4: []string{"3.3.3.3"}, TTL: 60, QueryTime: time.Now().Add(time.Second)})
+	cm1.doSaveResolveCache()
+	if data, _ := cm2.store.LoadResolveCache(); data.Records["a.com"] == nil {
+		t.Error("new record for a.com should replace the tombstone")
+	}
+}
+
+func TestCacheManager_JournalAccessStats(t *testing.T) {
+	config := DefaultConfig()
+	config.AccountID = "test123"
+	config.EnablePersistentCache = true
+	config.CacheDir = t.TempDir()
+	config.PersistentFlushInterval = 10 * time.Millisecond
+
+	cm := NewCacheManager(config)
+	cm.Set("hot.example.com", &CacheEntry{IPv4: []string{"1.2.3.4"}, TTL: 60, QueryTime: time.Now()})
+	for i := 0; i < 3; i++ {
+		cm.Get("hot.example.com")
+	}
+
+	// 访问统计随日志按防抖间隔写入，不依赖压缩或关闭
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		data, err := cm.store.LoadResolveCache()
+		if err == nil && data != nil && data.Stats["hot.example.com"] != nil && data.Stats["hot.example.com"].Hits == 4 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("journal stats = %+v, %v, want 4 hits for hot.example.com", data, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := os.Stat(filepath.Join(cm.store.(*FileStore).Dir(), resolveCacheFile)); !os.IsNotExist(err) {
+		t.Error("stats should be written to the journal without compaction")
+	}
+
+	// 模拟进程异常退出：不调用 SavePending，重新加载后统计仍在
+	reloaded := NewCacheManager(config)
+	if err := reloaded.LoadFromDisk(); err != nil {
+		t.Fatalf("LoadFromDisk() error = %v", err)
+	}
+	if stats := reloaded.accessStatsOf("hot.example.com"); stats.total != 4 {
+		t.Errorf("reloaded stats = %+v, want total 4", stats)
+	}
+}
+
+func TestClient_PersistentSync(t *testing.T) {
+	config := DefaultConfig()
+	config.AccountID = "test123"
+	config.EnablePersistentCache = true
+	config.CacheDir = t.TempDir()
+	config.PersistentSyncInterval = 20 * time.Millisecond
+
+	c1, err := NewClient(config)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	defer c1.Close()
+	c2, err := NewClient(config)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	defer c2.Close()
+
+	// 进程2 无需重启即可获取进程1 写入的记录
+	cm1 := c1.(*client).cacheManager
+	cm1.Set("peer.example.com", &CacheEntry{IPv4: []string{"1.2.3.4"}, TTL: 60, QueryTime: time.Now()})
+	cm1.SavePending()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, ok := c2.Cache().Get("peer.example.com"); ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("c2 should pick up the record written by c1")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestFileStore_Journal(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("NewFileStore() error = %v", err)
+	}
+	now := time.Now()
+
+	if err := store.SaveResolveCache(&ResolveCacheData{Records: map[string]*CacheEntry{
+		"snapshot.com": {IPv4: []string{"1.1.1.1"}, TTL: 60, QueryTime: now},
+		"deleted.com":  {IPv4: []string{"1.1.1.1"}, TTL: 60, QueryTime: now},
+	}}); err != nil {
+		t.Fatalf("SaveResolveCache() error = %v", err)
+	}
+	if err := store.AppendResolveCache([]JournalRecord{
+		{Domain: "a.com", Entry: &CacheEntry{IPv4: []string{"2.2.2.2"}, TTL: 60, QueryTime: now}, Time: now},
+		{Domain: "deleted.com", Time: now.Add(time.Second)},
+		{Domain: "b.com", Entry: &CacheEntry{IPv4: []string{"3.3.3.3"}, TTL: 60, QueryTime: now}, Time: now},
+	}); err != nil {
+		t.Fatalf("AppendResolveCache() error = %v", err)
+	}
+
+	data, err := store.LoadResolveCache()
+	if err != nil || data == nil {
+		t.Fatalf("LoadResolveCache() = %v, %v", data, err)
+	}
+	for _, domain := range []string{"snapshot.com", "a.com", "b.com"} {
+		if data.Records[domain] == nil {
+			t.Errorf("LoadResolveCache() missing %s", domain)
+		}
+	}
+	if data.Records["deleted.com"] != nil {
+		t.Error("deleted.com should be removed by the journal")
+	}
+
+	// 模拟写入过程中崩溃：截断最后一条记录
+	journalPath := filepath.Join(dir, resolveJournalFile)
+	info, err := os.Stat(journalPath)
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if err := os.Truncate(journalPath, info.Size()-10); err != nil {
+		t.Fatalf("Truncate() error = %v", err)
+	}
+
+	data, err = store.LoadResolveCache()
+	if err != nil {
+		t.Fatalf("LoadResolveCache() after truncation error = %v", err)
+	}
+	if data.Records["a.com"] == nil || data.Records["b.com"] != nil {
+		t.Errorf("LoadResolveCache() after truncation = %v, want a.com only from journal", data.Records)
+	}
+
+	// 截断后追加的记录不受残缺行影响
+	if err := store.AppendResolveCache([]JournalRecord{
+		{Domain: "c.com", Entry: &CacheEntry{IPv4: []string{"4.4.4.4"}, TTL: 60, QueryTime: now}, Time: now},
+	}); err != nil {
+		t.Fatalf("AppendResolveCache() error = %v", err)
+	}
+	data, err = store.LoadResolveCache()
+	if err != nil || data.Records["c.com"] == nil {
+		t.Errorf("LoadResolveCache() after append = %v, %v, want c.com", data, err)
+	}
+
+	// 保存完整快照时压缩日志
+	if err := store.SaveResolveCache(&ResolveCacheData{Records: map[string]*CacheEntry{}}); err != nil {
+		t.Fatalf("SaveResolveCache() error = %v", err)
+	}
+	if _, err := os.Stat(journalPath); !os.IsNotExist(err) {
+		t.Errorf("journal should be removed after compaction, Stat() error = %v", err)
+	}
+	if data, _ := store.LoadResolveCache(); len(data.Records) != 3 {
+		t.Errorf("records after compaction = %v, want 3", data.Records)
+	}
+}
+
+func TestFileStore_JournalDamagedLine(t *testing.T) {
+	now := time.Now()
+	records := []JournalRecord{
+		{Domain: "a.com", Entry: &CacheEntry{IPv4: []string{"1.1.1.1"}, TTL: 60, QueryTime: now}, Time: now},
+		{Domain: "b.com", Entry: &CacheEntry{IPv4: []string{"2.2.2.2"}, TTL: 60, QueryTime: now}, Time: now},
+	}
+
+	// 残缺的末尾行在追加前被截断，不会留在日志中间
+	dir := t.TempDir()
+	store, _ := NewFileStore(dir)
+	if err := store.AppendResolveCache(records); err != nil {
+		t.Fatalf("AppendResolveCache() error = %v", err)
+	}
+	journalPath := filepath.Join(dir, resolveJournalFile)
+	info, _ := os.Stat(journalPath)
+	os.Truncate(journalPath, info.Size()-10)
+	if err := store.AppendResolveCache(records[1:]); err != nil {
+		t.Fatalf("AppendResolveCache() error = %v", err)
+	}
+	data, err := store.LoadResolveCache()
+	if err != nil || data.Records["a.com"] == nil || data.Records["b.com"] == nil {
+		t.Fatalf("LoadResolveCache() after torn append = %+v, %v, want a.com and b.com", data, err)
+	}
+	content, _ := os.ReadFile(journalPath)
+	if lines := strings.Split(strings.TrimSpace(string(content)), "\n"); len(lines) != 3 {
+		t.Errorf("journal lines = %d, want header and 2 records:\n%s", len(lines), content)
+	}
+
+	// 日志中间无法解析的行视为损坏，隔离日志并计入指标
+	config := DefaultConfig()
+	config.AccountID = "test123"
+	config.EnablePersistentCache = true
+	config.EnableMetrics = true
+	config.CacheDir = t.TempDir()
+	dir = filepath.Join(config.CacheDir, config.AccountID)
+	if err := ensureCacheDir(dir); err != nil {
+		t.Fatalf("ensureCacheDir() error = %v", err)
+	}
+	store, _ = NewAccountFileStore(dir, config.AccountID, nil)
+	store.AppendResolveCache(records[:1])
+	f, _ := os.OpenFile(filepath.Join(dir, resolveJournalFile), os.O_APPEND|os.O_WRONLY, 0600)
+	f.WriteString("{garbage\n")
+	f.Close()
+	store.AppendResolveCache(records[1:])
+
+	if _, err := store.LoadResolveCache(); !errors.Is(err, ErrCorruptCache) {
+		t.Fatalf("LoadResolveCache() error = %v, want ErrCorruptCache", err)
+	}
+	if quarantined, _ := filepath.Glob(filepath.Join(dir, resolveJournalFile+".corrupt-*")); len(quarantined) != 1 {
+		t.Errorf("quarantined files = %v, want 1", quarantined)
+	}
+
+	store.AppendResolveCache(records[:1])
+	f, _ = os.OpenFile(filepath.Join(dir, resolveJournalFile), os.O_APPEND|os.O_WRONLY, 0600)
+	f.WriteString("{garbage\n")
+	f.Close()
+	if got := NewResolver(config).GetMetrics().CorruptCacheFiles; got != 1 {
+		t.Errorf("CorruptCacheFiles = %d, want 1", got)
+	}
+}
+
+func TestCacheManager_Journal(t *testing.T) {
+	config := DefaultConfig()
+	config.AccountID = "test123"
+	config.EnablePersistentCache = true
+	config.CacheDir = t.TempDir()
+	config.PersistentFlushInterval = 10 * time.Millisecond
+	config.PersistentCompactThreshold = 3
+
+	cm := NewCacheManager(config)
+	if cm.journal == nil {
+		t.Fatal("default file store should support journaling")
+	}
+	dir := cm.store.(*FileStore).Dir()
+
+	waitFor := func(desc string, cond func() bool) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for !cond() {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %s", desc)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(dir, name))
+		return err == nil
+	}
+
+	// 防抖间隔内的多次更新合并写入日志，不重写快照
+	cm.Set("a.com", &CacheEntry{IPv4: []string{"1.1.1.1"}, TTL: 60, QueryTime: time.Now()})
+	cm.SaveResolveCacheAsync()
+	cm.Set("b.com", &CacheEntry{IPv4: []string{"2.2.2.2"}, TTL: 60, QueryTime: time.Now()})
+	cm.SaveResolveCacheAsync()
+	waitFor("journal write", func() bool { return exists(resolveJournalFile) })
+	if exists(resolveCacheFile) {
+		t.Error("snapshot should not be rewritten for incremental updates")
+	}
+
+	// 日志记录数达到阈值后压缩
+	cm.Delete("a.com")
+	waitFor("compaction", func() bool { return exists(resolveCacheFile) && !exists(resolveJournalFile) })
+
+	cm.Set("c.com", &CacheEntry{IPv4: []string{"3.3.3.3"}, TTL: 60, QueryTime: time.Now()})
+	cm.SaveResolveCacheAsync()
+	cm.SavePending()
+
+	cm2 := NewCacheManager(config)
+	if err := cm2.LoadFromDisk(); err != nil {
+		t.Fatalf("LoadFromDisk() error = %v", err)
+	}
+	if got := cm2.Domains(); len(got) != 2 || got[0] != "b.com" || got[1] != "c.com" {
+		t.Errorf("Domains() after reload = %v, want [b.com c.com]", got)
+	}
+}
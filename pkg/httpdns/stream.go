@@ -0,0 +1,98 @@
+package httpdns
+
+import (
+	"context"
+	"sync"
+)
+
+// ResolveStream 流式解析域名
+// 从 domains 读取域名，按 MaxBatchDomains 聚合成批量请求，以 Concurrency 个 worker 并发解析，
+// 每个输入域名在返回的通道上产生一个结果（失败时 Error 非空）。
+// 输出通道无缓冲，消费方读取变慢时会逐级反压到输入端；domains 关闭且全部结果发送完毕，
+// 或 ctx 被取消时，输出通道关闭
+func (r *Resolver) ResolveStream(ctx context.Context, domains <-chan string, opts ...ResolveOption) <-chan *ResolveResult {
+	options := r.newResolveOptions(opts)
+
+	concurrency := options.Concurrency
+	if concurrency <= 0 {
+		concurrency = r.config.BatchConcurrency
+	}
+	if concurrency <= 0 {
+		concurrency = DefaultBatchConcurrency
+	}
+
+	batchSize := r.config.MaxBatchDomains
+	if batchSize <= 0 {
+		batchSize = DefaultMaxBatchDomains
+	}
+
+	batches := make(chan []string)
+	out := make(chan *ResolveResult)
+
+	// 聚合：阻塞等待第一个域名，再非阻塞地尽量凑满一批，避免为凑批而等待
+	go func() {
+		defer close(batches)
+		for {
+			var batch []string
+			select {
+			case <-ctx.Done():
+				return
+			case domain, ok := <-domains:
+				if !ok {
+					return
+				}
+				batch = append(batch, domain)
+			}
+
+			closed := false
+		fill:
+			for len(batch) < batchSize {
+				select {
+				case domain, ok := <-domains:
+					if !ok {
+						closed = true
+						break fill
+					}
+					batch = append(batch, domain)
+				default:
+					break fill
+				}
+			}
+
+			select {
+			case batches <- batch:
+			case <-ctx.Done():
+				return
+			}
+			if closed {
+				return
+			}
+		}
+	}()
+
+	// 解析：固定数量的 worker 消费批次
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				results, _ := r.ResolveBatch(ctx, batch, opts...)
+				for _, result := range results {
+					select {
+					case out <- result:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	return out
+}
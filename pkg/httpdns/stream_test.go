@@ -0,0 +1,139 @@
+package httpdns
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newStreamTestServer 创建批量解析测试服务器，记录每次请求携带的域名数
+func newStreamTestServer(t *testing.T, delay time.Duration) (*httptest.Server, func() []int) {
+	var (
+		mu    sync.Mutex
+		sizes []int
+	)
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/test123/ss" {
+			serverAddr := server.URL[7:]
+			response := map[string]interface{}{
+				"service_ip": []string{serverAddr},
+			}
+			json.NewEncoder(w).Encode(response)
+		} else if r.URL.Path == "/test123/resolve" {
+			hosts := strings.Split(r.URL.Query().Get("host"), ",")
+			mu.Lock()
+			sizes = append(sizes, len(hosts))
+			mu.Unlock()
+
+			time.Sleep(delay)
+
+			response := BatchResolveResponse{}
+			for _, host := range hosts {
+				response.DNS = append(response.DNS, HTTPDNSResponse{Host: host, IPs: []string{"1.1.1.1"}, TTL: 300})
+			}
+			json.NewEncoder(w).Encode(response)
+		} else {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() []int {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]int(nil), sizes...)
+	}
+}
+
+func TestResolver_ResolveStream(t *testing.T) {
+	server, batchSizes := newStreamTestServer(t, 0)
+
+	config := DefaultConfig()
+	config.AccountID = "test123"
+	config.BootstrapIPs = []string{server.URL[7:]}
+
+	resolver := NewResolver(config)
+
+	const total = 23
+	domains := make(chan string, total)
+	for i := 0; i < total; i++ {
+		domains <- fmt.Sprintf("domain%d.com", i)
+	}
+	close(domains)
+
+	seen := make(map[string]bool)
+	for result := range resolver.ResolveStream(context.Background(), domains, WithConcurrency(2)) {
+		if result.Error != nil {
+			t.Errorf("ResolveStream() result for %s has error: %v", result.Domain, result.Error)
+		}
+		seen[result.Domain] = true
+	}
+
+	if len(seen) != total {
+		t.Errorf("ResolveStream() produced %d distinct domains, want %d", len(seen), total)
+	}
+
+	sizes := batchSizes()
+	if len(sizes) >= total {
+		t.Errorf("ResolveStream() sent %d requests for %d domains, expected batching", len(sizes), total)
+	}
+	for _, size := range sizes {
+		if size > DefaultMaxBatchDomains {
+			t.Errorf("batch request carried %d domains, limit is %d", size, DefaultMaxBatchDomains)
+		}
+	}
+}
+
+func TestResolver_ResolveStream_Cancel(t *testing.T) {
+	server, _ := newStreamTestServer(t, 50*time.Millisecond)
+
+	config := DefaultConfig()
+	config.AccountID = "test123"
+	config.BootstrapIPs = []string{server.URL[7:]}
+	config.EnableMemoryCache = false
+
+	resolver := NewResolver(config)
+
+	// 输入通道永不关闭，只能通过 ctx 结束
+	domains := make(chan string)
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case domains <- fmt.Sprintf("domain%d.com", i):
+			case <-time.After(time.Second):
+				return
+			}
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	out := resolver.ResolveStream(ctx, domains, WithConcurrency(1))
+
+	// 读取少量结果后停止消费并取消
+	for i := 0; i < 3; i++ {
+		<-out
+	}
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		for range out {
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("ResolveStream() output channel not closed after ctx cancel")
+	}
+}
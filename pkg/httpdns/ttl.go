@@ -0,0 +1,89 @@
+package httpdns
+
+import (
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// defaultCacheTTL 服务端未返回有效TTL时使用的默认TTL（秒）
+const defaultCacheTTL = 60
+
+// TTLPolicy TTL策略
+type TTLPolicy struct {
+	MinTTL       time.Duration // TTL下限，默认0不限制
+	MaxTTL       time.Duration // TTL上限，默认0不限制
+	UseOriginTTL bool          // 使用权威DNS的原始TTL（origin_ttl），未返回时回退到 ttl
+	Jitter       float64       // 过期时间随机提前的最大比例（0-1），避免同一时刻集中刷新，默认0
+}
+
+// normalize 规范化策略参数
+func (p *TTLPolicy) normalize() {
+	if p.MinTTL < 0 {
+		p.MinTTL = 0
+	}
+	if p.MaxTTL < 0 {
+		p.MaxTTL = 0
+	}
+	if p.MaxTTL > 0 && p.MaxTTL < p.MinTTL {
+		p.MaxTTL = p.MinTTL
+	}
+	if p.Jitter < 0 || p.Jitter >= 1 {
+		p.Jitter = 0
+	}
+}
+
+// selectTTL 按策略选择服务端返回的TTL（秒）
+func (p *TTLPolicy) selectTTL(resp *HTTPDNSResponse) int {
+	if p.UseOriginTTL && resp.OriginTTL > 0 {
+		return resp.OriginTTL
+	}
+	return resp.TTL
+}
+
+// apply 对TTL（秒）进行上下限约束并加入随机抖动，返回实际缓存的TTL（秒）
+func (p *TTLPolicy) apply(ttl int) int {
+	if ttl <= 0 {
+		ttl = defaultCacheTTL
+	}
+
+	if min := int(p.MinTTL / time.Second); min > 0 && ttl < min {
+		ttl = min
+	}
+	if max := int(p.MaxTTL / time.Second); max > 0 && ttl > max {
+		ttl = max
+	}
+
+	// 在 [TTL*(1-Jitter), TTL] 内随机，抖动后至少保留1秒
+	if p.Jitter > 0 {
+		if spread := int(float64(ttl) * p.Jitter); spread > 0 {
+			ttl -= rand.Intn(spread + 1)
+		}
+		if ttl < 1 {
+			ttl = 1
+		}
+	}
+
+	return ttl
+}
+
+// ttlPolicy 返回域名适用的TTL策略：精确匹配优先，其次为最长的 *.后缀 匹配，否则使用全局策略
+func (c *Config) ttlPolicy(domain string) *TTLPolicy {
+	if len(c.DomainTTLPolicies) > 0 {
+		domain = normalizeDomain(domain)
+		if policy, ok := c.DomainTTLPolicies[domain]; ok {
+			return &policy
+		}
+		for suffix := domain; ; {
+			idx := strings.Index(suffix, ".")
+			if idx < 0 {
+				break
+			}
+			suffix = suffix[idx+1:]
+			if policy, ok := c.DomainTTLPolicies["*."+suffix]; ok {
+				return &policy
+			}
+		}
+	}
+	return &c.TTLPolicy
+}
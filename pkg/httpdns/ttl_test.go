@@ -0,0 +1,168 @@
+package httpdns
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTTLPolicy_Apply(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy TTLPolicy
+		ttl    int
+		want   int
+	}{
+		{name: "no policy", policy: TTLPolicy{}, ttl: 120, want: 120},
+		{name: "invalid ttl uses default", policy: TTLPolicy{}, ttl: 0, want: defaultCacheTTL},
+		{name: "clamp to min", policy: TTLPolicy{MinTTL: 30 * time.Second}, ttl: 10, want: 30},
+		{name: "clamp to max", policy: TTLPolicy{MaxTTL: 5 * time.Minute}, ttl: 3600, want: 300},
+		{name: "within bounds", policy: TTLPolicy{MinTTL: 30 * time.Second, MaxTTL: 5 * time.Minute}, ttl: 60, want: 60},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.apply(tt.ttl); got != tt.want {
+				t.Errorf("apply(%d) = %d, want %d", tt.ttl, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTTLPolicy_Jitter(t *testing.T) {
+	policy := TTLPolicy{Jitter: 0.2}
+
+	seen := make(map[int]bool)
+	for i := 0; i < 200; i++ {
+		got := policy.apply(100)
+		if got < 80 || got > 100 {
+			t.Fatalf("apply(100) with jitter = %d, want within [80, 100]", got)
+		}
+		seen[got] = true
+	}
+	if len(seen) < 2 {
+		t.Errorf("apply() with jitter produced %d distinct values, want randomized TTLs", len(seen))
+	}
+
+	// 极小的TTL抖动后至少保留1秒
+	small := TTLPolicy{Jitter: 0.9}
+	for i := 0; i < 50; i++ {
+		if got := small.apply(1); got < 1 {
+			t.Fatalf("apply(1) = %d, want >= 1", got)
+		}
+	}
+}
+
+func TestTTLPolicy_SelectTTL(t *testing.T) {
+	resp := &HTTPDNSResponse{TTL: 60, OriginTTL: 600}
+
+	if got := (&TTLPolicy{}).selectTTL(resp); got != 60 {
+		t.Errorf("selectTTL() = %d, want ttl 60", got)
+	}
+	if got := (&TTLPolicy{UseOriginTTL: true}).selectTTL(resp); got != 600 {
+		t.Errorf("selectTTL() = %d, want origin_ttl 600", got)
+	}
+	if got := (&TTLPolicy{UseOriginTTL: true}).selectTTL(&HTTPDNSResponse{TTL: 60}); got != 60 {
+		t.Errorf("selectTTL() without origin_ttl = %d, want fallback 60", got)
+	}
+}
+
+func TestConfig_TTLPolicyLookup(t *testing.T) {
+	config := DefaultConfig()
+	config.AccountID = "test123"
+	config.TTLPolicy = TTLPolicy{MaxTTL: time.Minute}
+	config.DomainTTLPolicies = map[string]TTLPolicy{
+		"API.Example.com.":  {MinTTL: 10 * time.Minute},
+		"*.example.com":     {MaxTTL: 2 * time.Minute},
+		"*.cdn.example.com": {MaxTTL: 3 * time.Minute, Jitter: 1.5},
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	tests := []struct {
+		domain string
+		want   time.Duration
+	}{
+		{domain: "api.example.com", want: 0},
+		{domain: "www.example.com", want: 2 * time.Minute},
+		{domain: "img.cdn.example.com", want: 3 * time.Minute},
+		{domain: "example.com", want: time.Minute},
+		{domain: "other.org", want: time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.domain, func(t *testing.T) {
+			if got := config.ttlPolicy(tt.domain).MaxTTL; got != tt.want {
+				t.Errorf("ttlPolicy(%s).MaxTTL = %v, want %v", tt.domain, got, tt.want)
+			}
+		})
+	}
+
+	// Validate 会规范化非法的抖动比例
+	if got := config.ttlPolicy("img.cdn.example.com").Jitter; got != 0 {
+		t.Errorf("Jitter = %v, want 0 after Validate", got)
+	}
+}
+
+func TestResolver_TTLPolicy(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/test123/ss" {
+			serverAddr := server.URL[7:]
+			response := map[string]interface{}{
+				"service_ip": []string{serverAddr},
+			}
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+		response := HTTPDNSResponse{Host: "example.com", IPs: []string{"1.2.3.4"}, TTL: 60, OriginTTL: 3600}
+		if r.URL.Path == "/test123/resolve" {
+			json.NewEncoder(w).Encode(BatchResolveResponse{DNS: []HTTPDNSResponse{response}})
+			return
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	config := DefaultConfig()
+	config.AccountID = "test123"
+	config.BootstrapIPs = []string{server.URL[7:]}
+	config.TTLPolicy = TTLPolicy{UseOriginTTL: true, MaxTTL: 10 * time.Minute}
+
+	resolver := NewResolver(config)
+
+	result, err := resolver.ResolveSingle(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("ResolveSingle() error = %v", err)
+	}
+	if result.TTL != 10*time.Minute {
+		t.Errorf("ResolveSingle() TTL = %v, want 10m", result.TTL)
+	}
+	if !result.ExpiresAt.Equal(result.Timestamp.Add(10 * time.Minute)) {
+		t.Errorf("ResolveSingle() ExpiresAt = %v, want Timestamp+10m", result.ExpiresAt)
+	}
+
+	// 缓存命中时返回相同的生效TTL和过期时间
+	cached, err := resolver.ResolveSingle(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("ResolveSingle() cached error = %v", err)
+	}
+	if cached.TTL != result.TTL || !cached.ExpiresAt.Equal(result.ExpiresAt) {
+		t.Errorf("cached TTL/ExpiresAt = %v/%v, want %v/%v", cached.TTL, cached.ExpiresAt, result.TTL, result.ExpiresAt)
+	}
+
+	// 批量解析同样应用TTL策略
+	resolver = NewResolver(config)
+	results, err := resolver.ResolveBatch(context.Background(), []string{"example.com"})
+	if err != nil {
+		t.Fatalf("ResolveBatch() error = %v", err)
+	}
+	if results[0].TTL != 10*time.Minute {
+		t.Errorf("ResolveBatch() TTL = %v, want 10m", results[0].TTL)
+	}
+}
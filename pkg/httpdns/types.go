@@ -2,8 +2,11 @@ package httpdns
 
 import (
 	"context"
+	"io"
 	"net"
 	"time"
+
+	"github.com/aliyun/alicloud-httpdns-go-sdk/internal/pool"
 )
 
 // Client 是HTTPDNS客户端的主接口
@@ -14,6 +17,15 @@ type Client interface {
 	// ResolveBatch 批量解析域名
 	ResolveBatch(ctx context.Context, domains []string, opts ...ResolveOption) ([]*ResolveResult, error)
 
+	// ResolveBatchMap 批量解析域名，返回以规范化域名为键的结果
+	ResolveBatchMap(ctx context.Context, domains []string, opts ...ResolveOption) (map[string]*ResolveResult, error)
+
+	// ResolveStream 流式解析域名，每个输入域名产生一个结果，ctx 取消或输入关闭后输出通道关闭
+	ResolveStream(ctx context.Context, domains <-chan string, opts ...ResolveOption) <-chan *ResolveResult
+
+	// Watch 监听域名的IP变化，仅在地址集合变化时发送事件，ctx 取消或客户端关闭后通道关闭
+	Watch(ctx context.Context, domain string, opts ...ResolveOption) <-chan ResolveEvent
+
 	// ResolveAsync 异步解析域名
 	ResolveAsync(ctx context.Context, domain string, callback func(*ResolveResult, error), opts ...ResolveOption)
 
@@ -32,8 +44,45 @@ type Client interface {
 	// GetServiceIPs 获取当前服务IP列表
 	GetServiceIPs() []string
 
+	// GetServiceIPStats 获取各服务IP的健康评分和熔断状态
+	GetServiceIPStats() []ServiceIPStats
+
 	// IsHealthy 检查客户端健康状态
 	IsHealthy() bool
+
+	// Health 返回客户端健康状态详情
+	Health() HealthStatus
+
+	// Cache 返回缓存管理接口
+	Cache() CacheAdmin
+
+	// ExportCache 导出版本化的缓存快照
+	ExportCache(w io.Writer) error
+
+	// ImportCache 按策略导入缓存快照，返回导入的条目数
+	ImportCache(r io.Reader, policy ImportPolicy) (int, error)
+}
+
+// ServiceIPStats 服务IP健康评分：延迟与错误率的 EWMA、综合评分和熔断状态
+type ServiceIPStats = pool.IPStats
+
+// BreakerState 服务IP熔断器状态
+type BreakerState = pool.BreakerState
+
+// 服务IP熔断器状态
+const (
+	BreakerClosed   = pool.BreakerClosed   // 正常，按评分参与选择
+	BreakerOpen     = pool.BreakerOpen     // 熔断，暂不使用
+	BreakerHalfOpen = pool.BreakerHalfOpen // 半开，允许一个探测请求
+)
+
+// HealthStatus 客户端健康状态详情
+type HealthStatus struct {
+	Healthy    bool          `json:"healthy"`     // 客户端是否运行中
+	ServiceIPs int           `json:"service_ips"` // 当前服务IP数量
+	ClockSkew  time.Duration `json:"clock_skew"`  // 估算的服务端时间 - 本地时间
+	// ClockSkewExceeded 偏差超过签名有效期，未经补偿的签名请求会被服务端拒绝（SDK 已自动补偿）
+	ClockSkewExceeded bool `json:"clock_skew_exceeded"`
 }
 
 // ResolveResult 解析结果
@@ -42,10 +91,12 @@ type ResolveResult struct {
 	ClientIP  string        // 客户端IP
 	IPv4      []net.IP      // IPv4地址列表
 	IPv6      []net.IP      // IPv6地址列表
-	TTL       time.Duration // TTL时间
+	TTL       time.Duration // 生效的TTL（应用TTL策略后）
+	ExpiresAt time.Time     // 缓存过期时间
 	Source    ResolveSource // 解析来源
 	Timestamp time.Time     // 解析时间戳
-	Error     error         // 错误信息
+	Stale     bool          // 是否为已过期的缓存结果
+	Error     error         // 错误信息（批量解析时记录单个域名的失败原因）
 }
 
 // ResolveSource 解析来源
@@ -70,9 +121,16 @@ type ResolveOption func(*ResolveOptions)
 
 // ResolveOptions 解析选项配置
 type ResolveOptions struct {
-	QueryType QueryType     // 查询类型
-	Timeout   time.Duration // 超时时间
-	ClientIP  string        // 客户端IP
+	QueryType   QueryType     // 查询类型
+	Timeout     time.Duration // 超时时间
+	ClientIP    string        // 客户端IP
+	Concurrency int           // 流式解析的并发数，默认使用 Config.BatchConcurrency
+
+	// MaxStale 本次调用可接受的过期缓存时长，覆盖 MaxStaleWhileRevalidate（nil 表示使用配置）
+	MaxStale *time.Duration
+
+	// RetryPolicy 本次调用的重试策略（nil 表示使用配置）
+	RetryPolicy RetryPolicy
 }
 
 // QueryType 查询类型，对应API中的query参数
@@ -119,6 +177,28 @@ func WithClientIP(ip string) ResolveOption {
 	}
 }
 
+// WithConcurrency 设置流式解析的并发数
+func WithConcurrency(n int) ResolveOption {
+	return func(opts *ResolveOptions) {
+		opts.Concurrency = n
+	}
+}
+
+// WithMaxStale 设置本次调用可接受的过期缓存时长
+// 过期不超过 d 的缓存会直接返回并触发后台刷新；网络失败时的兜底窗口取 d 与 MaxStaleIfError 的较大值
+func WithMaxStale(d time.Duration) ResolveOption {
+	return func(opts *ResolveOptions) {
+		opts.MaxStale = &d
+	}
+}
+
+// WithRetryPolicy 设置本次调用的重试策略，覆盖 Config.RetryPolicy
+func WithRetryPolicy(policy RetryPolicy) ResolveOption {
+	return func(opts *ResolveOptions) {
+		opts.RetryPolicy = policy
+	}
+}
+
 // HTTPDNSResponse EMAS HTTPDNS API响应结构
 type HTTPDNSResponse struct {
 	Host      string   `json:"host"`
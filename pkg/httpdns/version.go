@@ -0,0 +1,4 @@
+package httpdns
+
+// SDKVersion SDK 版本号，写入持久化文件头用于排查和兼容性判断
+const SDKVersion = "1.0.1"
@@ -0,0 +1,171 @@
+package httpdns
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+)
+
+const (
+	// watchRefreshFraction 在 TTL 过去该比例时刷新被监听的域名
+	watchRefreshFraction = 0.75
+	// watchMinInterval 两次刷新之间的最小间隔
+	watchMinInterval = time.Second
+	// watchMaxRetryInterval 解析失败后重试间隔的上限
+	watchMaxRetryInterval = 30 * time.Second
+)
+
+// ResolveEvent 域名IP变化事件
+type ResolveEvent struct {
+	Domain      string         // 域名
+	Result      *ResolveResult // 变化后的完整解析结果
+	AddedIPv4   []net.IP       // 新增的IPv4地址
+	RemovedIPv4 []net.IP       // 移除的IPv4地址
+	AddedIPv6   []net.IP       // 新增的IPv6地址
+	RemovedIPv6 []net.IP       // 移除的IPv6地址
+	Timestamp   time.Time      // 事件时间
+}
+
+// Watch 监听域名的IP变化
+// 在 TTL 到期前主动刷新域名（同时刷新缓存），仅当IPv4/IPv6地址集合发生变化时发送事件；
+// 首次解析成功时会发送一次包含全部地址的事件。ctx 取消后通道关闭
+func (r *Resolver) Watch(ctx context.Context, domain string, opts ...ResolveOption) <-chan ResolveEvent {
+	options := r.newResolveOptions(opts)
+	domain = normalizeDomain(domain)
+	events := make(chan ResolveEvent, 1)
+
+	go func() {
+		defer close(events)
+
+		var (
+			prev         *ResolveResult
+			retryBackoff = watchMinInterval
+		)
+
+		for {
+			fetchCtx, cancel := ctx, context.CancelFunc(func() {})
+			if options.Timeout > 0 {
+				fetchCtx, cancel = context.WithTimeout(ctx, options.Timeout)
+			}
+			result, err := r.fetchSingle(fetchCtx, domain, options)
+			cancel()
+			if errors.Is(err, ErrNoAddresses) {
+				// 启用负缓存时空结果以错误返回，按空地址集合处理，以便通知地址全部移除
+				result, err = emptyResolveResult(domain, options.ClientIP, r.config.NegativeCacheTTL), nil
+			}
+
+			var wait time.Duration
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				if r.config.Logger != nil {
+					r.config.Logger.Printf("Watch refresh failed for %s: %v", domain, err)
+				}
+				// 失败后指数退避重试
+				wait = retryBackoff
+				retryBackoff *= 2
+				if retryBackoff > watchMaxRetryInterval {
+					retryBackoff = watchMaxRetryInterval
+				}
+			} else {
+				retryBackoff = watchMinInterval
+				if event, changed := diffResolveResults(prev, result); changed {
+					select {
+					case events <- event:
+					case <-ctx.Done():
+						return
+					}
+				}
+				prev = result
+				wait = watchRefreshInterval(result.TTL)
+			}
+
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			}
+		}
+	}()
+
+	return events
+}
+
+// emptyResolveResult 创建不含地址的解析结果
+func emptyResolveResult(domain, clientIP string, ttl time.Duration) *ResolveResult {
+	now := time.Now()
+	return &ResolveResult{
+		Domain:    domain,
+		ClientIP:  clientIP,
+		TTL:       ttl,
+		Timestamp: now,
+		ExpiresAt: now.Add(ttl),
+		Source:    SourceHTTPDNS,
+	}
+}
+
+// watchRefreshInterval 根据TTL计算下一次刷新的等待时间
+func watchRefreshInterval(ttl time.Duration) time.Duration {
+	if ttl <= 0 {
+		ttl = 60 * time.Second
+	}
+	interval := time.Duration(float64(ttl) * watchRefreshFraction)
+	if interval < watchMinInterval {
+		interval = watchMinInterval
+	}
+	return interval
+}
+
+// diffResolveResults 比较两次解析结果的地址集合，返回变化事件及是否发生变化
+func diffResolveResults(prev, curr *ResolveResult) (ResolveEvent, bool) {
+	event := ResolveEvent{
+		Domain:    curr.Domain,
+		Result:    curr,
+		Timestamp: time.Now(),
+	}
+
+	var prevIPv4, prevIPv6 []net.IP
+	if prev != nil {
+		prevIPv4, prevIPv6 = prev.IPv4, prev.IPv6
+	}
+
+	event.AddedIPv4, event.RemovedIPv4 = diffIPs(prevIPv4, curr.IPv4)
+	event.AddedIPv6, event.RemovedIPv6 = diffIPs(prevIPv6, curr.IPv6)
+
+	changed := prev == nil ||
+		len(event.AddedIPv4) > 0 || len(event.RemovedIPv4) > 0 ||
+		len(event.AddedIPv6) > 0 || len(event.RemovedIPv6) > 0
+
+	return event, changed
+}
+
+// diffIPs 计算地址集合的差异（忽略顺序和重复）
+func diffIPs(prev, curr []net.IP) (added, removed []net.IP) {
+	prevSet := make(map[string]struct{}, len(prev))
+	for _, ip := range prev {
+		prevSet[ip.String()] = struct{}{}
+	}
+	currSet := make(map[string]struct{}, len(curr))
+	for _, ip := range curr {
+		key := ip.String()
+		if _, ok := currSet[key]; ok {
+			continue
+		}
+		currSet[key] = struct{}{}
+		if _, ok := prevSet[key]; !ok {
+			added = append(added, ip)
+		}
+	}
+	for _, ip := range prev {
+		key := ip.String()
+		if _, ok := currSet[key]; !ok {
+			removed = append(removed, ip)
+			currSet[key] = struct{}{} // 防止重复记录
+		}
+	}
+	return added, removed
+}
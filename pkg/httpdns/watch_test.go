@@ -0,0 +1,192 @@
+package httpdns
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestResolver_Watch(t *testing.T) {
+	var requestCount int32
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/test123/ss" {
+			serverAddr := server.URL[7:]
+			response := map[string]interface{}{
+				"service_ip": []string{serverAddr},
+			}
+			json.NewEncoder(w).Encode(response)
+		} else if r.URL.Path == "/test123/d" {
+			// 前两次返回相同地址，之后新增一个地址
+			ips := []string{"1.1.1.1"}
+			if atomic.AddInt32(&requestCount, 1) > 2 {
+				ips = []string{"1.1.1.1", "2.2.2.2"}
+			}
+			response := HTTPDNSResponse{
+				Host: "example.com",
+				IPs:  ips,
+				TTL:  1,
+			}
+			json.NewEncoder(w).Encode(response)
+		} else {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	config := DefaultConfig()
+	config.AccountID = "test123"
+	config.BootstrapIPs = []string{server.URL[7:]}
+
+	resolver := NewResolver(config)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := resolver.Watch(ctx, "example.com")
+
+	// 首次解析产生初始事件
+	select {
+	case event := <-events:
+		if len(event.AddedIPv4) != 1 || event.AddedIPv4[0].String() != "1.1.1.1" {
+			t.Errorf("initial event AddedIPv4 = %v, want [1.1.1.1]", event.AddedIPv4)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Watch() initial event timeout")
+	}
+
+	// 第二次刷新地址未变化，不应产生事件；第三次新增 2.2.2.2
+	select {
+	case event := <-events:
+		if atomic.LoadInt32(&requestCount) < 3 {
+			t.Errorf("Watch() emitted event without address change")
+		}
+		if len(event.AddedIPv4) != 1 || event.AddedIPv4[0].String() != "2.2.2.2" {
+			t.Errorf("change event AddedIPv4 = %v, want [2.2.2.2]", event.AddedIPv4)
+		}
+		if len(event.RemovedIPv4) != 0 {
+			t.Errorf("change event RemovedIPv4 = %v, want empty", event.RemovedIPv4)
+		}
+		if len(event.Result.IPv4) != 2 {
+			t.Errorf("change event Result.IPv4 = %v, want 2 addresses", event.Result.IPv4)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Watch() change event timeout")
+	}
+
+	cancel()
+	select {
+	case _, ok := <-events:
+		if ok {
+			// 可能有一个已在途的事件，继续等待关闭
+			if _, ok := <-events; ok {
+				t.Error("Watch() channel should be closed after ctx cancel")
+			}
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("Watch() channel not closed after ctx cancel")
+	}
+}
+
+func TestResolver_WatchNegativeCache(t *testing.T) {
+	var requestCount int32
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/test123/ss" {
+			json.NewEncoder(w).Encode(map[string]interface{}{"service_ip": []string{server.URL[7:]}})
+			return
+		}
+		// 首次返回地址，之后返回空结果
+		response := HTTPDNSResponse{Host: "example.com", TTL: 1}
+		if atomic.AddInt32(&requestCount, 1) == 1 {
+			response.IPs = []string{"1.1.1.1"}
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	config := DefaultConfig()
+	config.AccountID = "test123"
+	config.BootstrapIPs = []string{server.URL[7:]}
+	config.NegativeCacheTTL = time.Second
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := NewResolver(config).Watch(ctx, "example.com")
+
+	select {
+	case <-events:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Watch() initial event timeout")
+	}
+
+	// 启用负缓存时空结果仍产生地址全部移除的事件
+	select {
+	case event := <-events:
+		if len(event.RemovedIPv4) != 1 || event.RemovedIPv4[0].String() != "1.1.1.1" {
+			t.Errorf("event RemovedIPv4 = %v, want [1.1.1.1]", event.RemovedIPv4)
+		}
+		if len(event.Result.IPv4) != 0 || len(event.Result.IPv6) != 0 {
+			t.Errorf("event Result = %+v, want no addresses", event.Result)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Watch() removal event timeout")
+	}
+}
+
+func TestDiffResolveResults(t *testing.T) {
+	prev := &ResolveResult{
+		Domain: "example.com",
+		IPv4:   []net.IP{net.ParseIP("1.1.1.1"), net.ParseIP("2.2.2.2")},
+		IPv6:   []net.IP{net.ParseIP("2001:db8::1")},
+	}
+
+	// 顺序变化不算变化
+	same := &ResolveResult{
+		Domain: "example.com",
+		IPv4:   []net.IP{net.ParseIP("2.2.2.2"), net.ParseIP("1.1.1.1")},
+		IPv6:   []net.IP{net.ParseIP("2001:db8::1")},
+	}
+	if _, changed := diffResolveResults(prev, same); changed {
+		t.Error("diffResolveResults() reported change for reordered addresses")
+	}
+
+	curr := &ResolveResult{
+		Domain: "example.com",
+		IPv4:   []net.IP{net.ParseIP("1.1.1.1"), net.ParseIP("3.3.3.3")},
+	}
+	event, changed := diffResolveResults(prev, curr)
+	if !changed {
+		t.Fatal("diffResolveResults() should report change")
+	}
+	if len(event.AddedIPv4) != 1 || event.AddedIPv4[0].String() != "3.3.3.3" {
+		t.Errorf("AddedIPv4 = %v, want [3.3.3.3]", event.AddedIPv4)
+	}
+	if len(event.RemovedIPv4) != 1 || event.RemovedIPv4[0].String() != "2.2.2.2" {
+		t.Errorf("RemovedIPv4 = %v, want [2.2.2.2]", event.RemovedIPv4)
+	}
+	if len(event.RemovedIPv6) != 1 || event.RemovedIPv6[0].String() != "2001:db8::1" {
+		t.Errorf("RemovedIPv6 = %v, want [2001:db8::1]", event.RemovedIPv6)
+	}
+
+	// 首次结果视为变化
+	if _, changed := diffResolveResults(nil, curr); !changed {
+		t.Error("diffResolveResults() should report change for first result")
+	}
+}
+
+func TestWatchRefreshInterval(t *testing.T) {
+	if got := watchRefreshInterval(100 * time.Second); got != 75*time.Second {
+		t.Errorf("watchRefreshInterval(100s) = %v, want 75s", got)
+	}
+	if got := watchRefreshInterval(time.Second); got != watchMinInterval {
+		t.Errorf("watchRefreshInterval(1s) = %v, want %v", got, watchMinInterval)
+	}
+}